@@ -0,0 +1,13 @@
+package main
+
+//og:nocover
+func unreachable() int {
+	return 0
+}
+
+func partial(a int) int {
+	if a < 0 { //og:nocover
+		panic("negative")
+	}
+	return a
+}
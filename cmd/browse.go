@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	_ "embed" // to allow embedding strings
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/tanema/og/lib/results"
+	"github.com/tanema/og/lib/term"
+)
+
+//go:embed templates/browse.tmpl
+var browsetmpl string
+
+type (
+	// browser is the state of the interactive failure browser
+	browser struct {
+		Items    []*browseItem
+		Selected int
+		Expanded bool
+		Cfg      *Config
+		flags    []string
+	}
+	// browseItem is a failed test or a build error in the browser
+	browseItem struct {
+		Test  *results.Test
+		Build *results.BuildError
+		State results.Action
+	}
+)
+
+// browseFailures lets the failed tests and build errors of a set be looked
+// through with the keyboard, rerunning them or opening them in $EDITOR. Without
+// a terminal it only warns so that the exit code of the run is kept.
+func browseFailures(cmd *cobra.Command, cfg *Config, set *results.Set, args []string) error {
+	b := newBrowser(cfg, set, args)
+	if len(b.Items) == 0 {
+		return nil
+	} else if !term.IsTerminal(os.Stdin) || !term.IsTerminal(os.Stderr) {
+		warn(fmt.Errorf("browsing failures needs an interactive terminal"))
+		return nil
+	}
+	// reruns only run the selected test so they are never sharded or dumped
+	cmd.Flags().Set("shard", "")
+	cmd.Flags().Set("dump", "false")
+	screen, err := newBrowseScreen()
+	if err != nil {
+		return err
+	}
+	for {
+		if err := screen.RenderTmpl("browser", b); err != nil {
+			return err
+		}
+		key, err := term.ReadKey(os.Stdin)
+		if err != nil {
+			return err
+		}
+		item := b.Items[b.Selected]
+		switch key {
+		case term.KeyUp, "k":
+			b.move(-1)
+		case term.KeyDown, "j":
+			b.move(1)
+		case term.KeyEnter, " ":
+			b.Expanded = !b.Expanded
+		case "r":
+			if err := b.rerun(cmd, item); err != nil {
+				return err
+			}
+		case "e":
+			if err := openEditor(item.location()); err != nil {
+				return err
+			}
+		case "q", term.KeyEsc, term.KeyCtrlC:
+			return nil
+		default:
+			continue
+		}
+		if key == "r" || key == "e" {
+			// the screen was written over so start drawing below the new output
+			if screen, err = newBrowseScreen(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func newBrowser(cfg *Config, set *results.Set, args []string) *browser {
+	flags, _ := splitTestArgs(args)
+	b := &browser{Cfg: cfg, flags: []string{}}
+	for i := 0; i < len(flags); i++ {
+		if flags[i] == "-run" && i+1 < len(flags) {
+			i++
+		} else {
+			b.flags = append(b.flags, flags[i])
+		}
+	}
+	for _, builderr := range set.BuildErrors {
+		b.Items = append(b.Items, &browseItem{Build: builderr, State: results.Fail})
+	}
+	for _, test := range set.FailedTests {
+		b.Items = append(b.Items, &browseItem{Test: test, State: test.State})
+	}
+	return b
+}
+
+func newBrowseScreen() (*term.ScreenBuf, error) {
+	overrides, err := templateOverrides()
+	if err != nil {
+		return nil, err
+	}
+	return term.ParseScreenBuf(os.Stderr, append([]string{summarytmpl, browsetmpl}, overrides...)...)
+}
+
+func (b *browser) move(by int) {
+	b.Selected = (b.Selected + by + len(b.Items)) % len(b.Items)
+	b.Expanded = false
+}
+
+// rerun runs the selected test, or the package of a build error, on its own
+// and records whether it passes now.
+func (b *browser) rerun(cmd *cobra.Command, item *browseItem) error {
+	rerunCfg := *b.Cfg
+	rerunCfg.NoCover = true
+	rerunCfg.NoHistory = true
+	_, code, err := runCmd(cmd.Context(), cmd, &rerunCfg, append(b.flags[:len(b.flags):len(b.flags)], item.runArgs()...)...)
+	if err != nil {
+		return err
+	} else if code == exitOK {
+		item.State = results.Pass
+	} else {
+		item.State = results.Fail
+	}
+	return nil
+}
+
+// runArgs are the go test arguments that run just this item. Build errors rerun
+// their package, or the directory of the file if the package is not known.
+func (item *browseItem) runArgs() []string {
+	if item.Build != nil && item.Build.Package != "" {
+		return []string{item.Build.Package}
+	} else if item.Build != nil {
+		dir := filepath.Dir(item.Build.Path)
+		if !filepath.IsAbs(dir) && dir != "." {
+			dir = "./" + dir
+		}
+		return []string{dir}
+	}
+	parts := strings.Split(item.Test.Name, "/")
+	for i, part := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(part) + "$"
+	}
+	return []string{"-run", strings.Join(parts, "/"), item.Test.Package}
+}
+
+// location is the file and line that the item failed at
+func (item *browseItem) location() (string, int) {
+	if item.Build != nil {
+		return item.Build.Path, int(item.Build.Line)
+	}
+	for _, failure := range item.Test.Failures {
+		if failure.File != "" {
+			return packageFile(item.Test.Package, failure.File), failure.Line
+		} else if len(failure.PanicTrace) > 0 {
+			return failure.PanicTrace[0].Path, failure.PanicTrace[0].Line
+		}
+	}
+	return "", 0
+}
+
+// packageFile finds a file reported by go test, which is relative to the
+// directory of its package.
+func packageFile(pkg, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	loaded, err := packages.Load(&packages.Config{Mode: packages.NeedFiles}, pkg)
+	if err != nil || len(loaded) == 0 || len(loaded[0].GoFiles) == 0 {
+		return file
+	}
+	return filepath.Join(filepath.Dir(loaded[0].GoFiles[0]), filepath.Base(file))
+}
+
+// openEditor opens $EDITOR at the line of a file, falling back to vi
+func openEditor(file string, line int) error {
+	if file == "" {
+		return nil
+	}
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	args := editor[1:]
+	if line > 0 {
+		args = append(args, fmt.Sprintf("+%v", line))
+	}
+	editCmd := exec.Command(editor[0], append(args, file)...)
+	editCmd.Stdin, editCmd.Stdout, editCmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := editCmd.Run(); err != nil {
+		return fmt.Errorf("cannot open editor: %v", err)
+	}
+	return nil
+}
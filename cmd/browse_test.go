@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tanema/og/lib/results"
+)
+
+func TestNewBrowser(t *testing.T) {
+	set := results.New("./...", 0)
+	set.BuildErrors = []*results.BuildError{{Package: "bad", Path: "lib/bad/bad.go", Line: 3}}
+	set.FailedTests = []*results.Test{{Name: "TestA/with space", Package: "example.com/a", State: results.Fail}}
+
+	b := newBrowser(&Config{}, set, []string{"go", "test", "-json", "-v", "-short", "-run", "TestA|TestB", "./..."})
+	assert.Equal(t, []string{"go", "test", "-json", "-v", "-short"}, b.flags)
+	assert.Len(t, b.Items, 2)
+	assert.Equal(t, []string{"bad"}, b.Items[0].runArgs())
+	assert.Equal(t, []string{"./lib/bad"}, (&browseItem{Build: &results.BuildError{Path: "lib/bad/bad.go"}}).runArgs())
+	assert.Equal(t, []string{"/src/bad"}, (&browseItem{Build: &results.BuildError{Path: "/src/bad/bad.go"}}).runArgs())
+	assert.Equal(t, []string{"."}, (&browseItem{Build: &results.BuildError{Message: "no Go files"}}).runArgs())
+	assert.Equal(t, []string{"-run", `^TestA$/^with space$`, "example.com/a"}, b.Items[1].runArgs())
+
+	file, line := b.Items[0].location()
+	assert.Equal(t, "lib/bad/bad.go", file)
+	assert.Equal(t, 3, line)
+
+	b.move(-1)
+	assert.Equal(t, 1, b.Selected)
+	b.Expanded = true
+	b.move(1)
+	assert.Equal(t, 0, b.Selected)
+	assert.False(t, b.Expanded)
+}
+
+func TestBrowseFailuresWithoutTerminal(t *testing.T) {
+	set := results.New("./...", 0)
+	set.FailedTests = []*results.Test{{Name: "TestA", Package: "example.com/a", State: results.Fail}}
+	// go test never runs with a terminal on stdin, so this only warns
+	assert.Nil(t, browseFailures(nil, &Config{}, set, []string{"go", "test", "./..."}))
+}
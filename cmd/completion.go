@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// completeTargets completes the targets that og understands: package directories
+// and _test.go files, the tests in a file after file_test.go: and the names of
+// tests anywhere in the module.
+func completeTargets(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if i := strings.LastIndex(toComplete, ":"); i >= 0 {
+		path := toComplete[:i]
+		if !strings.HasSuffix(path, "_test.go") {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		completions := []string{}
+		for _, name := range findTestsInFile(path, -1) {
+			if strings.HasPrefix(name, toComplete[i+1:]) {
+				completions = append(completions, path+":"+name)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+	completions := completePaths(toComplete)
+	if strings.HasPrefix(toComplete, "Test") || strings.HasPrefix("Test", toComplete) {
+		completions = append(completions, moduleTests(toComplete)...)
+	}
+	if len(completions) == 1 && strings.HasSuffix(completions[0], "/") {
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePaths lists the directories and _test.go files that start with the
+// path being completed.
+func completePaths(toComplete string) []string {
+	dir, prefix := filepath.Split(toComplete)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+	completions := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || (entry.IsDir() && skipDir(name)) {
+			continue
+		} else if entry.IsDir() {
+			completions = append(completions, dir+name+"/")
+		} else if strings.HasSuffix(name, "_test.go") {
+			completions = append(completions, dir+name)
+		}
+	}
+	return completions
+}
+
+// moduleTests finds the names of all the tests in the module that start with prefix
+func moduleTests(prefix string) []string {
+	found := map[string]bool{}
+	filepath.WalkDir(moduleRoot(), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		} else if entry.IsDir() && path != moduleRoot() && skipDir(entry.Name()) {
+			return filepath.SkipDir
+		} else if !entry.IsDir() && strings.HasSuffix(path, "_test.go") {
+			for _, name := range findTestsInFile(path, -1) {
+				if strings.HasPrefix(name, prefix) {
+					found[name] = true
+				}
+			}
+		}
+		return nil
+	})
+	names := []string{}
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// skipDir is true for directories that go ignores when matching packages
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor"
+}
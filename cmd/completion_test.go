@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleteTargets(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/proj\n"), 0600))
+	for _, sub := range []string{"lib", "lib/testdata", "list", ".git"} {
+		assert.Nil(t, os.MkdirAll(filepath.Join(dir, sub), 0700))
+	}
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "lib", "a.go"), []byte("package lib\n"), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "lib", "a_test.go"), []byte("package lib\n\nfunc TestAdd(t *testing.T) {}\n\nfunc TestSub(t *testing.T) {}\n"), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "list", "b_test.go"), []byte("package list\n\nfunc TestAppend(t *testing.T) {}\n"), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "lib", "testdata", "c_test.go"), []byte("package c\n\nfunc TestHidden(t *testing.T) {}\n"), 0600))
+	assert.Nil(t, os.Chdir(dir))
+
+	completions, directive := completeTargets(rootCmd, nil, "li")
+	assert.Equal(t, []string{"lib/", "list/"}, completions)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+
+	completions, directive = completeTargets(rootCmd, nil, "lis")
+	assert.Equal(t, []string{"list/"}, completions)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace, directive)
+
+	completions, _ = completeTargets(rootCmd, nil, "lib/")
+	assert.Equal(t, []string{"lib/a_test.go"}, completions)
+
+	completions, _ = completeTargets(rootCmd, nil, "lib/a_test.go:")
+	assert.Equal(t, []string{"lib/a_test.go:TestAdd", "lib/a_test.go:TestSub"}, completions)
+	completions, _ = completeTargets(rootCmd, nil, "lib/a_test.go:TestS")
+	assert.Equal(t, []string{"lib/a_test.go:TestSub"}, completions)
+
+	completions, _ = completeTargets(rootCmd, nil, "TestA")
+	assert.Equal(t, []string{"TestAdd", "TestAppend"}, completions)
+	completions, _ = completeTargets(rootCmd, nil, "")
+	assert.Equal(t, []string{"lib/", "list/", "TestAdd", "TestAppend", "TestSub"}, completions)
+}
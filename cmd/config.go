@@ -0,0 +1,495 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed" // to allow embedding strings
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/tanema/og/lib/glob"
+	"github.com/tanema/og/lib/term"
+)
+
+const (
+	userConfigName    = "og.json"
+	projectConfigName = ".og.json"
+	envPrefix         = "OG_"
+)
+
+type (
+	// Duration is a time.Duration that can be written in the config as a string
+	// like "10s" as well as a number of nanoseconds.
+	Duration      time.Duration
+	configValue   struct{ Key, Value, Source string }
+	configProblem struct {
+		Path    string
+		Line    int
+		Message string
+	}
+)
+
+//go:embed templates/config.tmpl
+var configtmpl string
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show the config og runs with and check config files for mistakes",
+	Long: `Config is merged from the user config, the project .og.json, the selected
+profile, OG_ environment variables and flags.
+
+    - og config show                => every config value and where it came from
+    - og config validate            => check the user and project config files
+    - og config validate og.json    => check a single config file
+`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show every config value and where it was set",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return term.NewScreenBuf(os.Stderr, summarytmpl, configtmpl).RenderTmpl("config", cfg.values())
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [config.json...]",
+	Short: "Report unknown keys and bad values in config files",
+	// the config may not load, finding out why is the point of this command
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cfg.Load(cmd.Flags())
+		cfg.applyTheme()
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := args
+		if len(paths) == 0 {
+			paths = configPaths()
+		}
+		problems := []configProblem{}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if os.IsNotExist(err) && len(args) == 0 {
+				continue
+			} else if err != nil {
+				return fmt.Errorf("cannot read config file: %v", err)
+			}
+			problems = append(problems, validateConfig(path, data)...)
+		}
+		screen := term.NewScreenBuf(os.Stderr, summarytmpl, configtmpl)
+		if err := screen.RenderTmpl("config_problems", problems); err != nil {
+			return err
+		} else if len(problems) > 0 {
+			return &exitError{code: exitTestFailure}
+		}
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd, configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// Load merges the config from each of its layers, each one overriding the last:
+// the user config, the project config, the selected profile, OG_ environment
+// variables and lastly any flags that were set on the command line.
+func (config *Config) Load(flags *pflag.FlagSet) error {
+	root, _ = filepath.Abs("./")
+	config.sources = map[string]string{}
+	restore := changedFlags(flags)
+	flagProfile := ""
+	if flag := flags.Lookup("profile"); flag != nil && flag.Changed {
+		flagProfile = flag.Value.String()
+	}
+	for _, path := range configPaths() {
+		if err := config.loadFile(path); err != nil {
+			return err
+		}
+	}
+	profile := config.Profile
+	if name, ok := os.LookupEnv(envPrefix + "PROFILE"); ok {
+		profile = name
+	}
+	if flagProfile != "" {
+		profile = flagProfile
+	}
+	if profile != "" {
+		raw, ok := config.Profiles[profile]
+		if !ok {
+			return fmt.Errorf("undefined profile %v", profile)
+		} else if err := json.Unmarshal(raw, config); err != nil {
+			return fmt.Errorf("error while reading profile %v: %v", profile, err)
+		}
+		config.Profile = profile
+		config.setSources(raw, "profile "+profile)
+	}
+	if err := config.loadEnv(); err != nil {
+		return err
+	} else if err := restore(); err != nil {
+		return err
+	}
+	flags.Visit(func(flag *pflag.Flag) {
+		if key := config.flagKey(flag); key != "" {
+			config.sources[key] = "flag --" + flag.Name
+		}
+	})
+	if _, err := glob.CompileAll(config.CoverExclude); err != nil {
+		return fmt.Errorf("invalid cover_exclude: %v", err)
+	}
+	return nil
+}
+
+// configPaths are the config files in the order they are applied
+func configPaths() []string {
+	paths := []string{filepath.Join(userConfigDir(), userConfigName)}
+	if path := projectConfigPath(); path != "" {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// userConfigDir is $XDG_CONFIG_HOME, or ~/.config if it is not set
+func userConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	return os.ExpandEnv("$HOME/.config")
+}
+
+// projectConfigPath finds the closest .og.json from the working directory up to
+// the module root.
+func projectConfigPath() string {
+	dir, err := filepath.Abs("./")
+	if err != nil {
+		return ""
+	}
+	top := moduleRoot()
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		} else if dir == top || filepath.Dir(dir) == dir {
+			return ""
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func (config *Config) loadFile(path string) error {
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %v", err)
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return configError(path, data, err)
+	}
+	config.setSources(data, path)
+	return nil
+}
+
+// setSources records that the keys in the json object came from source
+func (config *Config) setSources(data []byte, source string) {
+	keys := map[string]json.RawMessage{}
+	json.Unmarshal(data, &keys)
+	for key := range keys {
+		config.sources[key] = source
+	}
+}
+
+// values lists every config value along with where it was set
+func (config *Config) values() []configValue {
+	values := []configValue{}
+	val := reflect.ValueOf(config).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		if val.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		key := jsonKey(val.Type().Field(i))
+		data, _ := json.Marshal(val.Field(i).Interface())
+		source, ok := config.sources[key]
+		if !ok {
+			source = "default"
+		}
+		values = append(values, configValue{Key: key, Value: string(data), Source: source})
+	}
+	return values
+}
+
+// flagKey finds the config key that a flag sets by comparing the address of its
+// value with the address of each config field.
+func (config *Config) flagKey(flag *pflag.Flag) string {
+	target := reflect.ValueOf(flag.Value)
+	if target.Kind() == reflect.Ptr && target.Elem().Kind() == reflect.Struct {
+		// slice flags wrap a pointer to the slice
+		target = target.Elem().Field(0)
+	}
+	if target.Kind() != reflect.Ptr {
+		return ""
+	}
+	val := reflect.ValueOf(config).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		if val.Field(i).Addr().Pointer() == target.Pointer() {
+			return jsonKey(val.Type().Field(i))
+		}
+	}
+	return ""
+}
+
+func jsonKey(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+// configError adds the line that a json error happened on to its message
+func configError(path string, data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("%v:%v: %v", path, lineAt(data, syntaxErr.Offset), err)
+	} else if errors.As(err, &typeErr) {
+		return fmt.Errorf("%v:%v: %v", path, lineAt(data, typeErr.Offset), err)
+	}
+	return fmt.Errorf("%v: %v", path, err)
+}
+
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return 1 + bytes.Count(data[:offset], []byte("\n"))
+}
+
+// validateConfig finds unknown keys, bad durations and values of the wrong type
+// in a config file.
+func validateConfig(path string, data []byte) []configProblem {
+	problems := []configProblem{}
+	problem := func(offset int64, format string, args ...interface{}) {
+		problems = append(problems, configProblem{Path: path, Line: lineAt(data, offset), Message: fmt.Sprintf(format, args...)})
+	}
+	keys := map[string]reflect.Type{}
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		keys[jsonKey(configType.Field(i))] = configType.Field(i).Type
+	}
+	offsets := map[string]int64{}
+	err := walkJSON(data, func(keyPath []string, offset int64, value json.Token) {
+		key := keyPath[len(keyPath)-1]
+		if len(keyPath) == 3 && keyPath[0] == "themes" {
+			if _, ok := term.Themes["default"][key]; !ok {
+				problem(offset, "unknown theme color %q in theme %v", key, keyPath[1])
+			}
+			return
+		} else if len(keyPath) != 1 && (len(keyPath) != 3 || keyPath[0] != "profiles") {
+			return
+		}
+		offsets[strings.Join(keyPath, ".")] = offset
+		typ, ok := keys[key]
+		if !ok || key == "" {
+			problem(offset, "unknown key %q", strings.Join(keyPath, "."))
+		} else if _, err := parseDuration(value); typ == reflect.TypeOf(Duration(0)) && err != nil {
+			problem(offset, "invalid duration %v for %v, use a string like \"10s\"", value, key)
+		}
+	})
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		problem(syntaxErr.Offset, "%v", err)
+		return problems
+	} else if err != nil {
+		problem(int64(len(data)), "%v", err)
+		return problems
+	}
+	// each value is decoded alone so that one bad value does not hide the rest
+	checkType := func(prefix string, raw map[string]json.RawMessage) {
+		for key, val := range raw {
+			typ, ok := keys[key]
+			if !ok || typ == reflect.TypeOf(Duration(0)) {
+				continue
+			} else if err := json.Unmarshal(val, reflect.New(typ).Interface()); err != nil {
+				problem(offsets[prefix+key], "%v should be %v, not %s", prefix+key, jsonTypeName(typ), val)
+			}
+		}
+	}
+	raw := map[string]json.RawMessage{}
+	json.Unmarshal(data, &raw)
+	checkType("", raw)
+	profiles := map[string]map[string]json.RawMessage{}
+	json.Unmarshal(raw["profiles"], &profiles)
+	for name, profile := range profiles {
+		checkType("profiles."+name+".", profile)
+	}
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems
+}
+
+func jsonTypeName(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Float64:
+		return "a number"
+	case reflect.Slice:
+		return "a list"
+	}
+	return "an object"
+}
+
+// walkJSON calls visit with the path, offset and value of every key in the json
+// objects. Objects and arrays are visited with their opening delimiter.
+func walkJSON(data []byte, visit func(keyPath []string, offset int64, value json.Token)) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	return walkToken(dec, tok, nil, visit)
+}
+
+func walkToken(dec *json.Decoder, tok json.Token, keyPath []string, visit func([]string, int64, json.Token)) error {
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+	for dec.More() {
+		elemPath := keyPath
+		if delim == '{' {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			elemPath = append(keyPath[:len(keyPath):len(keyPath)], fmt.Sprintf("%v", key))
+		}
+		offset := dec.InputOffset()
+		val, err := dec.Token()
+		if err != nil {
+			return err
+		} else if delim == '{' {
+			visit(elemPath, offset, val)
+		}
+		if err := walkToken(dec, val, elemPath, visit); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// UnmarshalJSON reads a duration string like "1m30s" or a number of nanoseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var val interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&val); err != nil {
+		return err
+	}
+	parsed, err := parseDuration(val)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalJSON writes the duration as a string like "10s"
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func parseDuration(val interface{}) (Duration, error) {
+	switch v := val.(type) {
+	case string:
+		d, err := time.ParseDuration(v)
+		return Duration(d), err
+	case json.Number:
+		n, err := v.Int64()
+		return Duration(n), err
+	}
+	return 0, fmt.Errorf("invalid duration %v", val)
+}
+
+// loadEnv sets config values from OG_ environment variables named after their
+// json keys, like OG_HIDE_ELAPSED. Lists are comma separated and maps are json.
+func (config *Config) loadEnv() error {
+	val := reflect.ValueOf(config).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		key := jsonKey(val.Type().Field(i))
+		name := envPrefix + strings.ToUpper(key)
+		str, ok := os.LookupEnv(name)
+		if !ok || val.Type().Field(i).PkgPath != "" {
+			continue
+		} else if err := setField(val.Field(i), str); err != nil {
+			return fmt.Errorf("invalid value for %v: %v", name, err)
+		}
+		config.sources[key] = "env " + name
+	}
+	return nil
+}
+
+func setField(field reflect.Value, str string) error {
+	if field.Type() == reflect.TypeOf(Duration(0)) {
+		d, err := time.ParseDuration(str)
+		field.SetInt(int64(d))
+		return err
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(str)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		field.SetBool(b)
+		return err
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(str, 64)
+		field.SetFloat(f)
+		return err
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(strings.Split(str, ",")))
+	default:
+		return json.Unmarshal([]byte(str), field.Addr().Interface())
+	}
+	return nil
+}
+
+// changedFlags remembers the flags that were set on the command line and
+// returns a func that sets them again once the config has been loaded. This
+// way flags only override the config when they were given explicitly.
+func changedFlags(flags *pflag.FlagSet) func() error {
+	values := map[*pflag.Flag][]string{}
+	flags.Visit(func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			values[flag] = append([]string{}, slice.GetSlice()...)
+		} else {
+			values[flag] = []string{flag.Value.String()}
+		}
+	})
+	return func() error {
+		for flag, vals := range values {
+			var err error
+			if slice, ok := flag.Value.(pflag.SliceValue); ok {
+				err = slice.Replace(vals)
+			} else {
+				err = flag.Value.Set(vals[0])
+			}
+			if err != nil {
+				return fmt.Errorf("invalid value for --%v: %v", flag.Name, err)
+			}
+		}
+		return nil
+	}
+}
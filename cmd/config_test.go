@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfigFlags(config *Config, args ...string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet("og", pflag.ContinueOnError)
+	flags.StringVarP(&config.Display, "display", "d", "dots", "")
+	flags.StringVarP(&config.Profile, "profile", "p", "", "")
+	flags.BoolVar(&config.Race, "race", false, "")
+	flags.StringSliceVar(&config.CoverExclude, "coverexclude", nil, "")
+	flags.Parse(args)
+	return flags
+}
+
+func TestLoadConfig(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	xdg, project := t.TempDir(), t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	assert.Nil(t, os.WriteFile(filepath.Join(xdg, "og.json"), []byte(`{
+		"display": "names",
+		"split": true,
+		"threshold": 5000000000,
+		"profiles": {"ci": {"display": "spin", "race": true, "tags": "integration", "timeout": "1m"}}
+	}`), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(project, "go.mod"), []byte("module example.com/proj\n"), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(project, ".og.json"), []byte(`{"split": false, "cover_exclude": ["*.pb.go"]}`), 0600))
+	assert.Nil(t, os.MkdirAll(filepath.Join(project, "lib"), 0700))
+	assert.Nil(t, os.Chdir(filepath.Join(project, "lib")))
+
+	t.Run("layers", func(t *testing.T) {
+		config := &Config{}
+		assert.Nil(t, config.Load(testConfigFlags(config)))
+		assert.Equal(t, "names", config.Display)
+		assert.False(t, config.Split)
+		assert.Equal(t, Duration(5*time.Second), config.Threshold)
+		assert.Equal(t, []string{"*.pb.go"}, config.CoverExclude)
+	})
+
+	t.Run("flags only override when set", func(t *testing.T) {
+		config := &Config{}
+		assert.Nil(t, config.Load(testConfigFlags(config, "--coverexclude", "mocks/")))
+		assert.Equal(t, "names", config.Display)
+		assert.Equal(t, []string{"mocks/"}, config.CoverExclude)
+
+		config = &Config{}
+		assert.Nil(t, config.Load(testConfigFlags(config, "-d", "bar")))
+		assert.Equal(t, "bar", config.Display)
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("OG_DISPLAY", "icons")
+		t.Setenv("OG_HIDE_ELAPSED", "true")
+		t.Setenv("OG_THRESHOLD", "2s")
+		t.Setenv("OG_COVER_EXCLUDE", "a.go,b/")
+		config := &Config{}
+		assert.Nil(t, config.Load(testConfigFlags(config)))
+		assert.Equal(t, "icons", config.Display)
+		assert.True(t, config.HideElapsed)
+		assert.Equal(t, Duration(2*time.Second), config.Threshold)
+		assert.Equal(t, []string{"a.go", "b/"}, config.CoverExclude)
+
+		config = &Config{}
+		assert.Nil(t, config.Load(testConfigFlags(config, "-d", "dots")))
+		assert.Equal(t, "dots", config.Display)
+
+		t.Setenv("OG_THRESHOLD", "soon")
+		assert.NotNil(t, (&Config{}).Load(testConfigFlags(&Config{})))
+
+		t.Setenv("OG_THRESHOLD", "2s")
+		t.Setenv("OG_COVER_EXCLUDE", "[z-a].go")
+		assert.NotNil(t, (&Config{}).Load(testConfigFlags(&Config{})))
+	})
+
+	t.Run("profiles", func(t *testing.T) {
+		config := &Config{}
+		assert.Nil(t, config.Load(testConfigFlags(config, "-p", "ci")))
+		assert.Equal(t, "ci", config.Profile)
+		assert.Equal(t, "spin", config.Display)
+		assert.True(t, config.Race)
+		assert.Equal(t, "integration", config.Tags)
+		assert.Equal(t, Duration(time.Minute), config.Timeout)
+
+		config = &Config{}
+		assert.Nil(t, config.Load(testConfigFlags(config, "-p", "ci", "-d", "bar")))
+		assert.Equal(t, "bar", config.Display)
+
+		t.Setenv("OG_PROFILE", "ci")
+		config = &Config{}
+		assert.Nil(t, config.Load(testConfigFlags(config)))
+		assert.Equal(t, "spin", config.Display)
+
+		config = &Config{}
+		assert.NotNil(t, config.Load(testConfigFlags(config, "-p", "nope")))
+	})
+}
+
+func TestProjectConfigPath(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	project := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(project, "go.mod"), []byte("module example.com/proj\n"), 0600))
+	assert.Nil(t, os.MkdirAll(filepath.Join(project, "a", "b"), 0700))
+	assert.Nil(t, os.Chdir(filepath.Join(project, "a", "b")))
+	assert.Equal(t, "", projectConfigPath())
+
+	assert.Nil(t, os.WriteFile(filepath.Join(project, ".og.json"), []byte(`{}`), 0600))
+	path, _ := filepath.EvalSymlinks(projectConfigPath())
+	expected, _ := filepath.EvalSymlinks(filepath.Join(project, ".og.json"))
+	assert.Equal(t, expected, path)
+}
+
+func TestConfigSources(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("OG_SPLIT", "true")
+	path := filepath.Join(xdg, "og.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"display": "names", "threshold": "3s"}`), 0600))
+
+	config := &Config{}
+	assert.Nil(t, config.Load(testConfigFlags(config, "--race", "--coverexclude", "a.go")))
+	sources := map[string]string{}
+	for _, val := range config.values() {
+		sources[val.Key] = val.Source
+		if val.Key == "threshold" {
+			assert.Equal(t, `"3s"`, val.Value)
+		}
+	}
+	assert.Equal(t, path, sources["display"])
+	assert.Equal(t, "env OG_SPLIT", sources["split"])
+	assert.Equal(t, "flag --race", sources["race"])
+	assert.Equal(t, "flag --coverexclude", sources["cover_exclude"])
+	assert.Equal(t, "default", sources["no_cover"])
+}
+
+func TestDurationJSON(t *testing.T) {
+	var d Duration
+	assert.Nil(t, json.Unmarshal([]byte(`"1m30s"`), &d))
+	assert.Equal(t, Duration(90*time.Second), d)
+	assert.Nil(t, json.Unmarshal([]byte(`1000`), &d))
+	assert.Equal(t, Duration(time.Microsecond), d)
+	assert.NotNil(t, json.Unmarshal([]byte(`"soon"`), &d))
+	assert.NotNil(t, json.Unmarshal([]byte(`true`), &d))
+	data, _ := json.Marshal(Duration(10 * time.Second))
+	assert.Equal(t, `"10s"`, string(data))
+}
+
+func TestValidateConfig(t *testing.T) {
+	problems := validateConfig("og.json", []byte(`{
+  "display": "names",
+  "threshhold": "10s",
+  "timeout": "soon",
+  "split": "yes",
+  "themes": {"mine": {"pas": "red"}},
+  "profiles": {"ci": {"taggs": "x", "threshold": 5}}
+}`))
+	assert.Equal(t, []configProblem{
+		{Path: "og.json", Line: 3, Message: `unknown key "threshhold"`},
+		{Path: "og.json", Line: 4, Message: `invalid duration soon for timeout, use a string like "10s"`},
+		{Path: "og.json", Line: 5, Message: `split should be true or false, not "yes"`},
+		{Path: "og.json", Line: 6, Message: `unknown theme color "pas" in theme mine`},
+		{Path: "og.json", Line: 7, Message: `unknown key "profiles.ci.taggs"`},
+	}, problems)
+
+	problems = validateConfig("og.json", []byte("{\n  \"threshold\": 10s\n}"))
+	assert.Len(t, problems, 1)
+	assert.Equal(t, 2, problems[0].Line)
+
+	assert.Empty(t, validateConfig("og.json", []byte(`{"threshold": "10s", "profiles": {"ci": {"race": true}}}`)))
+}
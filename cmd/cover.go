@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed" // to allow embedding strings
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tanema/og/lib/results"
+	"github.com/tanema/og/lib/term"
+)
+
+const lastCoverProfile = "cover.out"
+
+//go:embed templates/uncovered.tmpl
+var uncoveredtmpl string
+
+var coverCmd = &cobra.Command{
+	Use:   "cover --uncovered [pkg|file]",
+	Short: "Inspect the coverage from the last run",
+	Long: `Inspect the cover profile from the last run of og.
+
+    - og cover --uncovered                  => all uncovered blocks
+    - og cover --uncovered ./lib/results    => uncovered blocks in a package
+    - og cover --uncovered ./lib/...        => uncovered blocks in packages recursively
+    - og cover --uncovered ./lib/results/results.go => uncovered blocks in a file
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if uncovered, _ := cmd.Flags().GetBool("uncovered"); !uncovered {
+			return cmd.Help()
+		}
+		profile, err := lastCoverProfilePath(cfg)
+		if err != nil {
+			return err
+		}
+		files, err := results.Uncovered(profile, cfg.CoverExclude, args...)
+		if err != nil {
+			return err
+		}
+		return term.NewScreenBuf(os.Stderr, uncoveredtmpl).RenderTmpl("uncovered", files)
+	},
+}
+
+func init() {
+	coverCmd.Flags().BoolP("uncovered", "u", false, "list the statement blocks that were not covered")
+	rootCmd.AddCommand(coverCmd)
+}
+
+func coverArgs(cfg *Config, profile string) []string {
+	if cfg.NoCover {
+		return nil
+	}
+	args := []string{fmt.Sprintf("-coverprofile=%v", profile)}
+	if cfg.CoverPkg != "" {
+		args = append(args, fmt.Sprintf("-coverpkg=%v", cfg.CoverPkg))
+	}
+	if cfg.CoverMode != "" {
+		args = append(args, fmt.Sprintf("-covermode=%v", cfg.CoverMode))
+	}
+	return args
+}
+
+// newCoverProfile picks where go test will write the cover profile for a single
+// run. Unless a path was configured, it is put in a private temp dir so that
+// concurrent runs do not overwrite each other. The returned func cleans it up.
+func newCoverProfile(cfg *Config) (string, func(), error) {
+	if cfg.CoverProfile != "" {
+		return cfg.CoverProfile, func() {}, nil
+	}
+	dir, err := os.MkdirTemp("", "og-")
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot create cover profile: %v", err)
+	}
+	return filepath.Join(dir, lastCoverProfile), func() { os.RemoveAll(dir) }, nil
+}
+
+// saveCoverProfile keeps a copy of the profile from this run in the project
+// cache so that og cover can inspect it later. If the run did not produce a
+// profile the previous one is removed so it is never mistaken for this run.
+func saveCoverProfile(profile string) error {
+	last, err := lastCoverProfilePath(&Config{})
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(profile)
+	if os.IsNotExist(err) {
+		os.Remove(last)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return os.WriteFile(last, data, 0600)
+}
+
+// joinCoverProfiles combines the profiles of several go test runs into one, the
+// blocks that are in more than one profile are merged when the profile is read.
+func joinCoverProfiles(profile string, parts []string) error {
+	var joined bytes.Buffer
+	for _, part := range parts {
+		data, err := os.ReadFile(part)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("cannot read cover profile: %v", err)
+		}
+		os.Remove(part)
+		if joined.Len() > 0 && bytes.HasPrefix(data, []byte("mode: ")) {
+			data = data[bytes.IndexByte(data, '\n')+1:]
+		}
+		joined.Write(data)
+	}
+	if joined.Len() == 0 {
+		return nil
+	}
+	return os.WriteFile(profile, joined.Bytes(), 0600)
+}
+
+func lastCoverProfilePath(cfg *Config) (string, error) {
+	if cfg.CoverProfile != "" {
+		return cfg.CoverProfile, nil
+	}
+	dir, err := projectCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find cache dir: %v", err)
+	}
+	return filepath.Join(dir, lastCoverProfile), nil
+}
+
+// exportCoverage writes the cover profile out in any of the requested formats
+func exportCoverage(cfg *Config, profile string) error {
+	if cfg.LCOV != "" {
+		if err := writeCoverage(cfg.LCOV, profile, cfg, results.WriteLCOV); err != nil {
+			return err
+		}
+	}
+	if cfg.Cobertura != "" {
+		return writeCoverage(cfg.Cobertura, profile, cfg, results.WriteCobertura)
+	}
+	return nil
+}
+
+// writeCoverage renders the report before writing it so that a profile that
+// cannot be read never leaves a partial report behind.
+func writeCoverage(path, profile string, cfg *Config, write func(io.Writer, string, []string) error) error {
+	var report bytes.Buffer
+	if err := write(&report, profile, cfg.CoverExclude); err != nil {
+		return err
+	} else if err := os.WriteFile(path, report.Bytes(), 0644); err != nil {
+		return fmt.Errorf("cannot write coverage report: %v", err)
+	}
+	return nil
+}
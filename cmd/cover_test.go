@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tanema/og/lib/results"
+)
+
+func TestCoverArgs(t *testing.T) {
+	assert.Nil(t, coverArgs(&Config{NoCover: true, CoverPkg: "./..."}, "cover.out"))
+	assert.Equal(t, []string{"-coverprofile=cover.out"}, coverArgs(&Config{}, "cover.out"))
+	assert.Equal(t, []string{"-coverprofile=cover.out", "-coverpkg=./..."}, coverArgs(&Config{CoverPkg: "./..."}, "cover.out"))
+	assert.Equal(t, []string{"-coverprofile=cover.out", "-covermode=count"}, coverArgs(&Config{CoverMode: "count"}, "cover.out"))
+}
+
+func TestNewCoverProfile(t *testing.T) {
+	first, cleanupFirst, err := newCoverProfile(&Config{})
+	assert.Nil(t, err)
+	second, cleanupSecond, err := newCoverProfile(&Config{})
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, second)
+
+	assert.Nil(t, os.WriteFile(first, []byte("mode: set\n"), 0600))
+	cleanupFirst()
+	cleanupSecond()
+	_, err = os.Stat(filepath.Dir(first))
+	assert.True(t, os.IsNotExist(err))
+
+	kept, cleanup, err := newCoverProfile(&Config{CoverProfile: "./kept.out"})
+	assert.Nil(t, err)
+	assert.Equal(t, "./kept.out", kept)
+	cleanup()
+}
+
+func TestSaveCoverProfile(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	profile := filepath.Join(t.TempDir(), "cover.out")
+	assert.Nil(t, os.WriteFile(profile, []byte("mode: set\n"), 0600))
+
+	assert.Nil(t, saveCoverProfile(profile))
+	last, err := lastCoverProfilePath(&Config{})
+	assert.Nil(t, err)
+	data, err := os.ReadFile(last)
+	assert.Nil(t, err)
+	assert.Equal(t, "mode: set\n", string(data))
+
+	assert.Nil(t, saveCoverProfile(filepath.Join(t.TempDir(), "nope.out")))
+	_, err = os.Stat(last)
+	assert.True(t, os.IsNotExist(err))
+
+	last, err = lastCoverProfilePath(&Config{CoverProfile: "./kept.out"})
+	assert.Nil(t, err)
+	assert.Equal(t, "./kept.out", last)
+}
+
+func TestWriteCoverage(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "lcov.info")
+	assert.NotNil(t, writeCoverage(report, filepath.Join(dir, "nope.out"), &Config{}, results.WriteLCOV))
+	_, err := os.Stat(report)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Nil(t, os.WriteFile(report, []byte("last report\n"), 0644))
+	assert.NotNil(t, writeCoverage(report, filepath.Join(dir, "nope.out"), &Config{}, results.WriteLCOV))
+	data, _ := os.ReadFile(report)
+	assert.Equal(t, "last report\n", string(data))
+
+	profile := filepath.Join(dir, "cover.out")
+	assert.Nil(t, os.WriteFile(profile, []byte("mode: set\n"), 0600))
+	assert.Nil(t, writeCoverage(report, profile, &Config{}, results.WriteLCOV))
+	data, _ = os.ReadFile(report)
+	assert.NotEqual(t, "last report\n", string(data))
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tanema/og/lib/term"
+)
+
+const projectTemplatesPath = ".og/templates"
+
+// newScreen creates the screen for rendering test results with the display
+// from cfg and any custom templates.
+func newScreen(cfg *Config) (*term.ScreenBuf, error) {
+	display, err := loadDisplay(cfg.Display)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := templateOverrides()
+	if err != nil {
+		return nil, err
+	}
+	screen, err := term.ParseScreenBuf(os.Stderr, append([]string{summarytmpl, display}, overrides...)...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse templates: %v", err)
+	}
+	return screen, nil
+}
+
+// applyTheme sets the colors used by templates to the theme named in the config.
+// Themes in the config are looked up before the built in ones.
+func (config *Config) applyTheme() error {
+	theme, ok := config.Themes[config.Theme]
+	if !ok {
+		if theme, ok = term.Themes[config.Theme]; !ok {
+			return fmt.Errorf("undefined theme %v", config.Theme)
+		}
+	}
+	if err := term.SetTheme(theme); err != nil {
+		return fmt.Errorf("invalid theme %v: %v", config.Theme, err)
+	}
+	return nil
+}
+
+// templateDirs are the directories that custom templates are loaded from, the
+// project templates come last so that they take precedence over the user's.
+func templateDirs() []string {
+	return []string{
+		filepath.Join(userConfigDir(), "og", "templates"),
+		filepath.Join(moduleRoot(), projectTemplatesPath),
+	}
+}
+
+// loadDisplay finds the progress display template by name. Custom displays are
+// read from progress/<name>.tmpl in the template dirs, before the built in ones.
+func loadDisplay(name string) (string, error) {
+	dirs := templateDirs()
+	for i := len(dirs) - 1; i >= 0; i-- {
+		data, err := os.ReadFile(filepath.Join(dirs[i], "progress", name+".tmpl"))
+		if err == nil {
+			return string(data), nil
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("cannot read display %v: %v", name, err)
+		}
+	}
+	data, err := displays.ReadFile(fmt.Sprintf("templates/progress/%v.tmpl", name))
+	if err != nil {
+		return "", fmt.Errorf("undefined display %v", name)
+	}
+	return string(data), nil
+}
+
+// templateOverrides reads all of the templates at the top of the template dirs.
+// They can redefine any of the summary templates, like "failures".
+func templateOverrides() ([]string, error) {
+	sources := []string{}
+	for _, dir := range templateDirs() {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read template %v: %v", path, err)
+			}
+			sources = append(sources, string(data))
+		}
+	}
+	return sources, nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tanema/og/lib/term"
+)
+
+func TestLoadDisplay(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	dir := filepath.Join(home, ".config", "og", "templates")
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "progress"), 0700))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "progress", "mine.tmpl"), []byte(`{{define "pass"}}+{{end}}`), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "failures.tmpl"), []byte(`{{define "failures"}}oops{{end}}`), 0600))
+
+	display, err := loadDisplay("mine")
+	assert.Nil(t, err)
+	assert.Equal(t, `{{define "pass"}}+{{end}}`, display)
+
+	display, err = loadDisplay("dots")
+	assert.Nil(t, err)
+	assert.Contains(t, display, `{{define "pass"}}`)
+
+	_, err = loadDisplay("nope")
+	assert.NotNil(t, err)
+
+	overrides, err := templateOverrides()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`{{define "failures"}}oops{{end}}`}, overrides)
+
+	_, err = newScreen(&Config{Display: "mine"})
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte(`{{define "failures"}}`), 0600))
+	_, err = newScreen(&Config{Display: "mine"})
+	assert.NotNil(t, err)
+}
+
+func TestApplyTheme(t *testing.T) {
+	defer term.SetTheme(nil)
+	assert.Nil(t, (&Config{Theme: "colorblind"}).applyTheme())
+	assert.Nil(t, (&Config{Theme: "mine", Themes: map[string]term.Theme{"mine": {"pass": "bold 42"}}}).applyTheme())
+	assert.NotNil(t, (&Config{Theme: "nope"}).applyTheme())
+	assert.NotNil(t, (&Config{Theme: "mine", Themes: map[string]term.Theme{"mine": {"pass": "purple"}}}).applyTheme())
+}
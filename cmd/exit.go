@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/tanema/og/lib/results"
+)
+
+// Exit codes that og will exit with so that scripts and CI can tell apart why
+// a run did not succeed.
+const (
+	exitOK            = 0 // all tests passed
+	exitTestFailure   = 1 // one or more tests failed
+	exitBuildFailure  = 2 // a package or test binary failed to build
+	exitInternalError = 3 // og itself failed, like a bad flag or config
+)
+
+// exitError stops the command with a specific exit code. The reason has
+// already been rendered to the user so there is no message.
+type exitError struct {
+	code int
+}
+
+func (err *exitError) Error() string {
+	return fmt.Sprintf("exit status %v", err.code)
+}
+
+// exitCode turns the error from running the command into an exit code, printing
+// the error if it was not a test result.
+func exitCode(err error) int {
+	var exit *exitError
+	if err == nil {
+		return exitOK
+	} else if errors.As(err, &exit) {
+		return exit.code
+	}
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	return exitInternalError
+}
+
+// warn prints a problem that does not change the result of the run
+func warn(err error) {
+	fmt.Fprintln(os.Stderr, "Warning:", err)
+}
+
+// resultCode decides the exit code for a completed run, failed is true when go
+// test itself exited with a failure.
+func resultCode(set *results.Set, failed bool) int {
+	if !failed && set.State != results.Fail {
+		return exitOK
+	} else if len(set.BuildErrors) > 0 {
+		return exitBuildFailure
+	}
+	return exitTestFailure
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tanema/og/lib/results"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, exitOK, exitCode(nil))
+	assert.Equal(t, exitTestFailure, exitCode(&exitError{code: exitTestFailure}))
+	assert.Equal(t, exitBuildFailure, exitCode(fmt.Errorf("wrapped: %w", &exitError{code: exitBuildFailure})))
+	assert.Equal(t, exitInternalError, exitCode(fmt.Errorf("bad config")))
+}
+
+func TestResultCode(t *testing.T) {
+	set := results.New("", time.Minute)
+	set.Add(results.Pass, "nope", "TestA", "")
+	set.Add(results.Pass, "nope", "", "")
+	set.Complete(false, "")
+	assert.Equal(t, exitOK, resultCode(set, false))
+	assert.Equal(t, exitTestFailure, resultCode(set, true))
+
+	// a stream that was cut off before the test finished
+	set = results.New("", time.Minute)
+	set.Add(results.Run, "nope", "TestA", "")
+	set.Complete(false, "")
+	assert.Equal(t, exitTestFailure, resultCode(set, false))
+
+	set = results.New("", time.Minute)
+	set.Add(results.Fail, "nope", "TestA", "")
+	set.Add(results.Fail, "nope", "", "")
+	set.Complete(false, "")
+	assert.Equal(t, exitTestFailure, resultCode(set, true))
+
+	set = results.New("", time.Minute)
+	set.ParseError([]byte("# nope"))
+	set.Complete(false, "")
+	assert.Equal(t, exitOK, resultCode(set, false))
+	assert.Equal(t, exitBuildFailure, resultCode(set, true))
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	_ "embed" // to allow embedding strings
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tanema/og/lib/history"
+	"github.com/tanema/og/lib/results"
+	"github.com/tanema/og/lib/term"
+)
+
+//go:embed templates/history.tmpl
+var historytmpl string
+
+var historyCmd = &cobra.Command{
+	Use:   "history [--tests [filter]|--coverage]",
+	Short: "Show past runs and trends for this project",
+	Long: `Every run of og is recorded for the project so that you can see how the
+suite changes over time.
+
+    - og history                  => list past runs
+    - og history --tests          => duration trend and failure count of every test
+    - og history --tests TestFoo  => trends for tests matching TestFoo
+    - og history --coverage       => coverage of every package over time
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openHistory()
+		if err != nil {
+			return err
+		}
+		runs, err := store.List()
+		if err != nil {
+			return err
+		}
+		if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 && len(runs) > limit {
+			runs = runs[:limit]
+		}
+		screen := term.NewScreenBuf(os.Stderr, summarytmpl, historytmpl)
+		if tests, _ := cmd.Flags().GetBool("tests"); tests {
+			return screen.RenderTmpl("test_trends", history.TestTrends(runs, strings.Join(args, " ")))
+		} else if coverage, _ := cmd.Flags().GetBool("coverage"); coverage {
+			return screen.RenderTmpl("coverage_trends", history.CoverageTrends(runs))
+		}
+		return screen.RenderTmpl("runs", runs)
+	},
+}
+
+func init() {
+	historyCmd.Flags().BoolP("tests", "t", false, "show duration trends and failure counts per test")
+	historyCmd.Flags().BoolP("coverage", "c", false, "show coverage per package over time")
+	historyCmd.Flags().IntP("limit", "l", 20, "only use this many of the most recent runs, 0 for all")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func openHistory() (*history.Store, error) {
+	dir, err := projectCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("cannot find cache dir: %v", err)
+	}
+	return history.Open(filepath.Join(dir, "history"))
+}
+
+// saveHistory records the finished set along with what it was run with. The
+// cover blocks and intervals are left out since history never reads them.
+func saveHistory(set *results.Set, args []string) error {
+	store, err := openHistory()
+	if err != nil {
+		return err
+	}
+	return store.Save(&history.Run{
+		GoVersion: goVersion(),
+		GitSHA:    gitSHA(),
+		Target:    runTarget(args),
+		Flags:     historyFlags(args),
+		Set:       set.Compact(),
+	})
+}
+
+// historyFlags are the go test flags of a run, without the go command and the
+// test filter and shard that are part of the target.
+func historyFlags(args []string) []string {
+	flags, _ := splitTestArgs(args)
+	recorded := []string{}
+	for i := 2; i < len(flags); i++ {
+		if flags[i] == "-run" {
+			i++
+		} else if flags[i] != "-shard" {
+			recorded = append(recorded, flags[i])
+		}
+	}
+	return recorded
+}
+
+// lastRunChanges compares the set to the last recorded run of the same target.
+// The comparison is best effort, if there is no previous run there are no changes.
+func lastRunChanges(set *results.Set, args []string, cfg *Config) *results.Changes {
+	store, err := openHistory()
+	if err != nil {
+		return nil
+	}
+	last, err := store.Last(runTarget(args))
+	if err != nil || last == nil {
+		return nil
+	}
+	return set.Diff(last.Set, cfg.Regression)
+}
+
+// runTarget is the packages, test filter and shard of a go test command, this
+// is used to compare runs of the same tests.
+func runTarget(args []string) string {
+	target := []string{}
+	for i := 2; i < len(args); i++ {
+		if (args[i] == "-run" || args[i] == "-shard") && i+1 < len(args) {
+			target = append(target, args[i], args[i+1])
+			i++
+		} else if args[i] == "-shuffle" {
+			i++
+		} else if !strings.HasPrefix(args[i], "-") {
+			target = append(target, args[i])
+		}
+	}
+	return strings.Join(target, " ")
+}
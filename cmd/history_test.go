@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunTarget(t *testing.T) {
+	assert.Equal(t, "./...", runTarget([]string{"go", "test", "-json", "-v", "./..."}))
+	assert.Equal(t, "-run TestA|TestB ./lib", runTarget([]string{"go", "test", "-json", "-v", "-shuffle", "on", "-run", "TestA|TestB", "./lib"}))
+	assert.Equal(t, "./a ./b", runTarget([]string{"go", "test", "-count=1", "./a", "./b"}))
+	assert.Equal(t, "./... -shard 2/5", runTarget([]string{"go", "test", "-json", "./...", "-shard", "2/5"}))
+}
+
+func TestHistoryFlags(t *testing.T) {
+	assert.Equal(t, []string{"-json", "-v", "-shuffle", "on"}, historyFlags([]string{"go", "test", "-json", "-v", "-shuffle", "on", "-run", "TestA|TestB", "./lib"}))
+	assert.Equal(t, []string{"-json", "-race"}, historyFlags([]string{"go", "test", "-json", "-race", "./...", "-shard", "2/5"}))
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tanema/og/lib/results"
+	"github.com/tanema/og/lib/term"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge dump.json...",
+	Short: "Combine the results saved with --dump into one",
+	Long: `Combine results that were saved with og --dump, like from sharded CI jobs,
+into a single result that can be rendered with og report. Tests that ran in
+more than one of the results are reported as conflicts.
+
+    - og merge shard1.json shard2.json > merged.json
+    - og report merged.json
+`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sets := []*results.Set{}
+		for _, path := range args {
+			set, err := loadReport(path)
+			if err != nil {
+				return err
+			}
+			sets = append(sets, set)
+		}
+		merged := results.Merge(sets...)
+		if len(merged.Conflicts) > 0 {
+			if err := term.NewScreenBuf(os.Stderr, summarytmpl).RenderTmpl("conflicts", merged.Conflicts); err != nil {
+				return err
+			}
+		}
+		return dumpJSON(merged)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// moduleRoot walks up from the current directory to find the directory that
+// contains go.mod. If there is no module the current directory is used.
+func moduleRoot() string {
+	dir, err := filepath.Abs("./")
+	if err != nil {
+		return "."
+	}
+	for current := dir; ; current = filepath.Dir(current) {
+		if info, err := os.Stat(filepath.Join(current, "go.mod")); err == nil && !info.IsDir() {
+			return current
+		} else if filepath.Dir(current) == current {
+			return dir
+		}
+	}
+}
+
+// projectCacheDir is the directory in the user cache where og keeps state for
+// the current project, like the last cover profile.
+func projectCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	root := moduleRoot()
+	sum := sha1.Sum([]byte(root))
+	dir := filepath.Join(cacheDir, "og", fmt.Sprintf("%v-%x", filepath.Base(root), sum[:4]))
+	return dir, os.MkdirAll(dir, 0700)
+}
+
+// gitSHA is the current commit of the project, empty if it is not a git repo
+func gitSHA() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// goVersion is the version of the go toolchain that will run the tests
+func goVersion() string {
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
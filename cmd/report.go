@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/tanema/og/lib/results"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report [dump.json]",
+	Short: "Render the results saved with --dump",
+	Long: `Render results that were saved with og --dump as if the tests were just
+run. The display flags can be used to render it differently than it was run.
+
+    - og --dump > dump.json
+    - og report dump.json --display names --split
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		set, err := loadReport(args[0])
+		if err != nil {
+			return err
+		}
+		reportCfg := *cfg
+		reportCfg.NoCover = cfg.NoCover || set.StatementCount == 0
+		screen, err := newScreen(&reportCfg)
+		if err != nil {
+			return err
+		} else if err := screen.RenderTmpl("summary", renderData{Set: set, Cfg: &reportCfg}); err != nil {
+			return err
+		} else if code := resultCode(set, false); code != exitOK {
+			return &exitError{code: code}
+		}
+		return nil
+	},
+}
+
+func init() {
+	addDisplayFlags(reportCmd.Flags())
+	rootCmd.AddCommand(reportCmd)
+}
+
+// addDisplayFlags adds the flags that change how results are rendered
+func addDisplayFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&cfg.Display, "display", "d", "dots", "change the display of the test output [dots,names,icons,bar,spin] or a custom display")
+	flags.BoolVarP(&cfg.Split, "split", "s", false, "show progress split up by package")
+	flags.BoolVarP(&cfg.HideExcerpts, "hideexcerpts", "x", false, "hide code excerpts in build errors")
+	flags.BoolVarP(&cfg.HideElapsed, "hideelapse", "e", false, "hide the elapsed time output")
+	flags.BoolVarP(&cfg.NoCover, "nocover", "c", false, "disable coverage")
+	flags.BoolVar(&cfg.Timeline, "timeline", false, "show when each package and test ran and paused")
+}
+
+func loadReport(path string) (*results.Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read report: %v", err)
+	}
+	set := &results.Set{}
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("cannot read report %v: %v", path, err)
+	}
+	return set, nil
+}
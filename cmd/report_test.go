@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadReport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dump.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"version":1,"name":"nope","state":"fail","threshold":1000000000,"packages":{"nope":{"name":"nope","state":"fail","elapsed":2000000}}}`), 0600))
+	set, err := loadReport(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "nope", set.Name)
+	assert.Equal(t, exitTestFailure, resultCode(set, false))
+	assert.Equal(t, "2ms", set.Packages["nope"].Elapsed().String())
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"version":99}`), 0600))
+	_, err = loadReport(path)
+	assert.NotNil(t, err)
+
+	_, err = loadReport(filepath.Join(dir, "missing.json"))
+	assert.NotNil(t, err)
+}
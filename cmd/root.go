@@ -2,12 +2,12 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"embed"
 	_ "embed" // to allow embedding strings
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -21,30 +21,50 @@ import (
 
 	"github.com/tanema/og/lib/results"
 	"github.com/tanema/og/lib/term"
-	"github.com/tanema/og/lib/watch"
 )
 
 const (
 	major = 0
 	minor = 1
-
-	configPath = "$HOME/.config/og.json"
-	coverPath  = "/tmp/cover.out"
 )
 
 type (
 	renderData struct {
-		Set *results.Set
-		Cfg *Config
+		Set     *results.Set
+		Cfg     *Config
+		Changes *results.Changes
 	}
 	// Config captures running config from flags and global config
 	Config struct {
-		Display      string        `json:"display"`
-		Split        bool          `json:"split"`
-		HideExcerpts bool          `json:"hide_excerpts"`
-		HideElapsed  bool          `json:"hide_elapsed"`
-		Threshold    time.Duration `json:"threshold"`
-		NoCover      bool          `json:"no_cover"`
+		Display      string                     `json:"display"`
+		Split        bool                       `json:"split"`
+		HideExcerpts bool                       `json:"hide_excerpts"`
+		HideElapsed  bool                       `json:"hide_elapsed"`
+		Threshold    Duration                   `json:"threshold"`
+		NoCover      bool                       `json:"no_cover"`
+		CoverPkg     string                     `json:"coverpkg"`
+		CoverExclude []string                   `json:"cover_exclude"`
+		WatchIgnore  []string                   `json:"watch_ignore"`
+		Poll         bool                       `json:"poll"`
+		CoverMode    string                     `json:"covermode"`
+		LCOV         string                     `json:"lcov"`
+		Cobertura    string                     `json:"cobertura"`
+		CoverProfile string                     `json:"coverprofile"`
+		NoHistory    bool                       `json:"no_history"`
+		Regression   float64                    `json:"regression"`
+		Timeline     bool                       `json:"timeline"`
+		Theme        string                     `json:"theme"`
+		Themes       map[string]term.Theme      `json:"themes"`
+		Short        bool                       `json:"short"`
+		NoCache      bool                       `json:"no_cache"`
+		FailFast     bool                       `json:"failfast"`
+		Shuffle      bool                       `json:"shuffle"`
+		Race         bool                       `json:"race"`
+		Tags         string                     `json:"tags"`
+		Timeout      Duration                   `json:"timeout"`
+		Profile      string                     `json:"profile"`
+		Profiles     map[string]json.RawMessage `json:"profiles"`
+		sources      map[string]string
 	}
 )
 
@@ -57,14 +77,15 @@ var (
 	versiontmpl     string
 	version         string
 	cfg             = &Config{}
-	cmdMut          sync.Mutex
 	testFuncPattern = regexp.MustCompile(`func (Test.*)\(t \*testing\.T\)`)
 	root            string
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "og [path[:[lineNum|TestName]]|TestName]",
-	Short: "Run go test but make it colorful",
+	Use:               "og [path[:[lineNum|TestName]]|TestName]",
+	Short:             "Run go test but make it colorful",
+	Args:              cobra.ArbitraryArgs,
+	ValidArgsFunction: completeTargets,
 	Long: `Go's test output can sometimes be quit hard to parse, and harder to scan.
 A common solution to this is syntax highlighting. It makes it easy to scan
 and notice what exactly is wrong at a glance. og test does this.
@@ -80,165 +101,323 @@ The easy autocomplete works as follows.
 Any further go flags can be passed with a -- suffix
 
     og -- -vet=atomic
+
+Output that was already generated with go test -json can be read instead of
+running the tests
+
+    go test -json ./... | og -
+    og --from events.json
+
+og exits with 0 when all tests pass, 1 when tests fail, 2 when a package
+fails to build and 3 when og itself fails.
 `,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		cobra.CheckErr(cfg.Load())
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := cfg.Load(cmd.Flags()); err != nil {
+			return err
+		}
+		return cfg.applyTheme()
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if version, _ := cmd.Flags().GetBool("version"); version {
 			printVersion(cfg)
-			return
+			return nil
+		}
+		if from, _ := cmd.Flags().GetString("from"); from != "" || (len(args) == 1 && args[0] == "-") {
+			code, err := readCmd(cmd, cfg, from)
+			if err == nil && code != exitOK {
+				return &exitError{code: code}
+			}
+			return err
 		}
 		testargs, err := fmtTestArgs(cmd, cfg, args...)
-		cobra.CheckErr(err)
-		cobra.CheckErr(runCmd(cmd, cfg, testargs...))
-		if watch, _ := cmd.Flags().GetBool("watch"); watch {
-			cobra.CheckErr(watchTestChanges(cmd, cfg, args))
+		if err != nil {
+			return err
 		}
+		set, code, err := runCmd(cmd.Context(), cmd, cfg, testargs...)
+		if err != nil {
+			return err
+		} else if watch, _ := cmd.Flags().GetBool("watch"); watch {
+			return watchTestChanges(cmd, cfg, testargs, set)
+		} else if interactive, _ := cmd.Flags().GetBool("interactive"); interactive {
+			if err := browseFailures(cmd, cfg, set, testargs); err != nil {
+				return err
+			}
+		}
+		if code != exitOK {
+			return &exitError{code: code}
+		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.Flags().BoolP("dump", "D", false, "dumps the final state in json for usage")
 	rootCmd.Flags().BoolP("watch", "w", false, "watch for file changes and re-run tests")
-	rootCmd.Flags().Bool("short", false, "run short tests")
-	rootCmd.Flags().Bool("nocache", false, "disable go test cache")
-	rootCmd.Flags().Bool("failfast", false, "terminate after first test failure")
-	rootCmd.Flags().Bool("shuffle", false, "shuffle test order")
+	rootCmd.Flags().StringSliceVar(&cfg.WatchIgnore, "watchignore", nil, "globs of directories and files to not watch, .gitignore files are always respected")
+	rootCmd.Flags().BoolVar(&cfg.Poll, "poll", false, "poll for file changes when watching, for mounts that do not send file system events")
+	rootCmd.Flags().BoolP("interactive", "i", false, "browse the failures after the run to rerun or edit them")
+	rootCmd.Flags().BoolVar(&cfg.Short, "short", false, "run short tests")
+	rootCmd.Flags().BoolVar(&cfg.NoCache, "nocache", false, "disable go test cache")
+	rootCmd.Flags().BoolVar(&cfg.FailFast, "failfast", false, "terminate after first test failure")
+	rootCmd.Flags().BoolVar(&cfg.Shuffle, "shuffle", false, "shuffle test order")
+	rootCmd.Flags().BoolVar(&cfg.Race, "race", false, "enable the race detector")
+	rootCmd.Flags().StringVar(&cfg.Tags, "tags", "", "comma separated build tags to test with")
+	rootCmd.Flags().DurationVar((*time.Duration)(&cfg.Timeout), "timeout", 0, "panic if a test binary runs longer than this, 0 uses the go test default")
 	rootCmd.Flags().BoolP("version", "v", false, "print cmd version")
-
-	rootCmd.Flags().StringVarP(&cfg.Display, "display", "d", "dots", "change the display of the test output [dots,names,icons,bar,spin]")
-	rootCmd.Flags().BoolVarP(&cfg.Split, "split", "s", false, "show progress split up by package")
-	rootCmd.Flags().BoolVarP(&cfg.HideExcerpts, "hideexcerpts", "x", false, "hide code excerpts in build errors")
-	rootCmd.Flags().BoolVarP(&cfg.HideElapsed, "hideelapse", "e", false, "hide the elapsed time output")
-	rootCmd.Flags().DurationVarP(&cfg.Threshold, "threshold", "r", 10*time.Second, "output lists of tests slower than the threshold. 0 will disable")
-	rootCmd.Flags().BoolVarP(&cfg.NoCover, "nocover", "c", false, "disable coverage")
+	rootCmd.Flags().String("from", "", "read go test -json output from this file instead of running go test, - for stdin")
+	rootCmd.Flags().String("shard", "", "only run part of the tests, like 2/5 for the second of five shards")
+	rootCmd.Flags().String("sharddurations", "", "balance shards with the times in this --dump instead of splitting by hash")
+
+	addDisplayFlags(rootCmd.Flags())
+	rootCmd.Flags().DurationVarP((*time.Duration)(&cfg.Threshold), "threshold", "r", 10*time.Second, "output lists of tests slower than the threshold. 0 will disable")
+	rootCmd.Flags().StringVar(&cfg.CoverPkg, "coverpkg", "./...", "packages to measure coverage in, empty will only cover tested packages")
+	rootCmd.Flags().StringVar(&cfg.CoverMode, "covermode", "", "coverage mode [set,count,atomic], count will export real hit counts")
+	rootCmd.Flags().StringVar(&cfg.LCOV, "lcov", "", "write the coverage as an lcov tracefile to this path")
+	rootCmd.Flags().StringVar(&cfg.Cobertura, "cobertura", "", "write the coverage as a cobertura xml report to this path")
+	rootCmd.Flags().BoolVar(&cfg.NoHistory, "nohistory", false, "do not record this run in the project history")
+	rootCmd.Flags().Float64Var(&cfg.Regression, "regression", 1.5, "report tests that got slower than the last run by this ratio. 0 will disable")
+	rootCmd.PersistentFlags().StringVar(&cfg.CoverProfile, "coverprofile", "", "keep the cover profile at this path instead of a temp file")
+	rootCmd.PersistentFlags().StringVarP(&cfg.Profile, "profile", "p", "", "use a named profile from the config")
+	rootCmd.PersistentFlags().StringVar(&cfg.Theme, "theme", "default", "color theme [default,colorblind] or a theme from the config")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.CoverExclude, "coverexclude", nil, "globs of files to exclude from coverage, generated files are always excluded")
 }
 
 // Execute is the main entry into the cli
 func Execute(ver string) {
 	version = strings.TrimSpace(ver)
-	rootCmd.Execute()
+	os.Exit(exitCode(rootCmd.Execute()))
 }
 
-// Load will load global config from config path
-func (config *Config) Load() error {
-	root, _ = filepath.Abs("./")
-	path := os.ExpandEnv(configPath)
-	if info, err := os.Stat(path); err != nil || info.IsDir() {
-		return nil
+// runCmd runs go test and renders the results, returning the set and the exit
+// code for the run. An error is only returned if og failed to run the tests, or
+// the context was cancelled which kills go test and skips the summary.
+func runCmd(ctx context.Context, cmd *cobra.Command, cfg *Config, args ...string) (*results.Set, int, error) {
+	profile, cleanup, err := newCoverProfile(cfg)
+	if err != nil {
+		return nil, exitInternalError, err
+	}
+	defer cleanup()
+
+	set := results.New(args[len(args)-1], time.Duration(cfg.Threshold))
+	flags, _ := splitTestArgs(args)
+	for i := 0; i+1 < len(flags); i++ {
+		if flags[i] == "-run" {
+			set.Filter = flags[i+1]
+		}
 	}
-	data, err := os.ReadFile(path)
+	screen, err := newScreen(cfg)
 	if err != nil {
-		return fmt.Errorf("cannot read config file: %v", err)
+		return nil, exitInternalError, err
+	}
+	commands := [][]string{args}
+	if shardFlag, _ := cmd.Flags().GetString("shard"); shardFlag != "" {
+		durationsPath, _ := cmd.Flags().GetString("sharddurations")
+		if commands, set.Shard, err = shardCommands(shardFlag, durationsPath, args); err != nil {
+			return nil, exitInternalError, err
+		}
+		// the shard is part of the history target so shards are compared to themselves
+		args = append(args[:len(args):len(args)], "-shard", shardFlag)
 	}
 
-	if err := json.Unmarshal(data, config); err != nil {
-		return fmt.Errorf("error while reading config file [%v]: %v", configPath, err)
+	failed := false
+	render := progressRenderer(screen, set, cfg)
+	profiles := []string{}
+	for i, command := range commands {
+		commandProfile := profile
+		if len(commands) > 1 {
+			commandProfile = fmt.Sprintf("%v.%v", profile, i)
+			profiles = append(profiles, commandProfile)
+		}
+		runErr := runGoTest(ctx, command, coverArgs(cfg, commandProfile), render, set.ParseError)
+		if ctx.Err() != nil {
+			return nil, exitInternalError, ctx.Err()
+		} else if _, isExit := runErr.(*exec.ExitError); runErr != nil && !isExit {
+			return nil, exitInternalError, fmt.Errorf("cannot run go test: %v", runErr)
+		}
+		failed = failed || runErr != nil
 	}
-	return nil
+	if len(profiles) > 0 && !cfg.NoCover {
+		if err := joinCoverProfiles(profile, profiles); err != nil {
+			return nil, exitInternalError, err
+		}
+	}
+	if err := set.Complete(!cfg.NoCover, profile, cfg.CoverExclude...); err != nil {
+		return nil, exitInternalError, fmt.Errorf("cannot read cover profile: %v", err)
+	}
+	if err := screen.RenderTmpl("summary", renderData{Set: set, Cfg: cfg, Changes: lastRunChanges(set, args, cfg)}); err != nil {
+		return nil, exitInternalError, err
+	}
+	if !cfg.NoCover {
+		// the copy for og cover is a convenience so it cannot fail the run
+		if err := saveCoverProfile(profile); err != nil {
+			warn(fmt.Errorf("cannot keep cover profile for og cover: %v", err))
+		}
+		if err := exportCoverage(cfg, profile); err != nil {
+			return nil, exitInternalError, err
+		}
+	}
+	if !cfg.NoHistory {
+		if err := saveHistory(set, args); err != nil {
+			warn(fmt.Errorf("cannot record run in history: %v", err))
+		}
+	}
+	if dump, _ := cmd.Flags().GetBool("dump"); dump {
+		if cfg.Timeline {
+			set.IncludeTimeline()
+		}
+		if err := dumpJSON(set); err != nil {
+			return nil, exitInternalError, err
+		}
+	}
+	return set, resultCode(set, failed), nil
 }
 
-func runCmd(cmd *cobra.Command, cfg *Config, args ...string) error {
-	cmdMut.Lock()
-	defer cmdMut.Unlock()
-
+// runGoTest runs a single go test command, passing each line of its output and
+// errors to the callbacks. If the context can be cancelled go test is started in
+// its own process group so that cancelling also kills the test binaries.
+func runGoTest(ctx context.Context, args, coverArgs []string, onOutput, onError func([]byte)) error {
 	stdReader, stdWriter := io.Pipe()
 	defer stdReader.Close()
 	errReader, errWriter := io.Pipe()
 	defer errReader.Close()
 
-	set := results.New(args[len(args)-1], cfg.Threshold)
-	tmpl, err := displays.Open(fmt.Sprintf("templates/progress/%v.tmpl", cfg.Display))
-	if err != nil {
-		return fmt.Errorf("undefined display %v", cfg.Display)
-	}
-	display, _ := ioutil.ReadAll(tmpl)
-	screen := term.NewScreenBuf(os.Stderr, summarytmpl, string(display))
-	gocmd := exec.Command(args[0], args[1:]...)
+	testArgs := append(append([]string{args[1]}, coverArgs...), args[2:]...)
+	gocmd := exec.Command(args[0], testArgs...)
 	gocmd.Env = os.Environ()
 	gocmd.Stderr = errWriter
 	gocmd.Stdout = stdWriter
+	if ctx.Done() != nil {
+		setProcessGroup(gocmd)
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(2)
-	go consume(&wg, stdReader, func(data []byte) {
-		set.Parse(data)
-		screen.RenderTmpl("results", renderData{set, cfg})
-	})
-	go consume(&wg, errReader, set.ParseError)
-
-	gocmd.Run()
+	go consume(&wg, stdReader, onOutput)
+	go consume(&wg, errReader, onError)
+
+	err := gocmd.Start()
+	if err == nil {
+		stop := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				killProcessGroup(gocmd)
+			case <-stop:
+			}
+		}()
+		err = gocmd.Wait()
+		close(stop)
+	}
 	stdWriter.Close()
 	errWriter.Close()
 	wg.Wait()
-	set.Complete(!cfg.NoCover, coverPath)
-	if err := screen.RenderTmpl("summary", renderData{set, cfg}); err != nil {
-		return err
-	}
-	if dump, _ := cmd.Flags().GetBool("dump"); dump {
-		return dumpJSON(set)
-	}
-	return nil
+	return err
 }
 
-func consume(wg *sync.WaitGroup, r io.Reader, fn func([]byte)) {
-	defer wg.Done()
-	scanner := bufio.NewScanner(r)
-	scanner.Split(bufio.ScanLines)
-	for scanner.Scan() {
-		fn(scanner.Bytes())
+// readCmd renders go test -json output from a file, or stdin if the path is
+// empty or -, as if og had run the tests. Since there is no go invocation the
+// coverage is only shown if a cover profile was passed with --coverprofile, and
+// the run is not recorded in the history.
+func readCmd(cmd *cobra.Command, cfg *Config, path string) (int, error) {
+	input := os.Stdin
+	if path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return exitInternalError, fmt.Errorf("cannot read test output: %v", err)
+		}
+		defer file.Close()
+		input = file
 	}
-}
 
-func watchTestChanges(cmd *cobra.Command, cfg *Config, args []string) error {
-	term.Println(`{{"Watching" | bold | Green}} press {{"ctr-t"| blue}} to re-run tests`, nil)
-	watcher, err := watch.New()
+	readCfg := *cfg
+	readCfg.NoCover = cfg.NoCover || cfg.CoverProfile == ""
+	set := results.New(path, time.Duration(cfg.Threshold))
+	set.Replay()
+	screen, err := newScreen(&readCfg)
 	if err != nil {
-		return err
+		return exitInternalError, err
 	}
-	go watcher.Start()
 
-	for {
-		select {
-		case path, ok := <-watcher.Changes:
-			if !ok {
-				return nil
-			}
-			path = strings.ReplaceAll(path, root, ".")
-			term.Println(`{{"Running" | bold | Magenta}} {{.Path | bold}} [{{.Time}}]`, struct{ Time, Path string }{Time: now(), Path: path})
-			args, err := fmtTestArgs(cmd, cfg, path)
-			if err != nil {
-				return err
+	var wg sync.WaitGroup
+	wg.Add(1)
+	consume(&wg, input, progressRenderer(screen, set, &readCfg))
+	if err := set.Complete(!readCfg.NoCover, readCfg.CoverProfile, readCfg.CoverExclude...); err != nil {
+		return exitInternalError, fmt.Errorf("cannot read cover profile: %v", err)
+	}
+	if err := screen.RenderTmpl("summary", renderData{Set: set, Cfg: &readCfg}); err != nil {
+		return exitInternalError, err
+	}
+	if !readCfg.NoCover {
+		if err := exportCoverage(&readCfg, readCfg.CoverProfile); err != nil {
+			return exitInternalError, err
+		}
+	}
+	if dump, _ := cmd.Flags().GetBool("dump"); dump {
+		if cfg.Timeline {
+			set.IncludeTimeline()
+		}
+		if err := dumpJSON(set); err != nil {
+			return exitInternalError, err
+		}
+	}
+	return resultCode(set, false), nil
+}
+
+// progressRenderer parses each line of go test output. On a terminal the
+// progress display is redrawn on every event, otherwise a line is appended for
+// each package as it finishes so that logs stay readable.
+func progressRenderer(screen *term.ScreenBuf, set *results.Set, cfg *Config) func([]byte) {
+	done := map[string]bool{}
+	return func(data []byte) {
+		set.Parse(data)
+		if screen.Interactive() {
+			screen.RenderTmpl("results", renderData{Set: set, Cfg: cfg})
+			return
+		} else if len(done) == set.PkgSummary.Pass+set.PkgSummary.Fail+set.PkgSummary.Skip {
+			return
+		}
+		for name, pkg := range set.Packages {
+			if !done[name] && (pkg.State == results.Pass || pkg.State == results.Fail || pkg.State == results.Skip) {
+				done[name] = true
+				screen.RenderTmpl("package_done", pkg)
 			}
-			return runCmd(cmd, cfg, args...)
-		case err := <-watcher.Errors:
-			return err
 		}
 	}
 }
 
-func now() string {
-	current := time.Now()
-	return fmt.Sprintf("%02d:%02d:%02d", current.Hour(), current.Minute(), current.Second())
+func consume(wg *sync.WaitGroup, r io.Reader, fn func([]byte)) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		fn(scanner.Bytes())
+	}
 }
 
 func fmtTestArgs(cmd *cobra.Command, cfg *Config, args ...string) ([]string, error) {
 	testArgs := []string{"go", "test", "-json", "-v"}
-	if nocache, _ := cmd.Flags().GetBool("nocache"); nocache {
+	if cfg.NoCache {
 		testArgs = append(testArgs, "-count=1")
 	}
-	if short, _ := cmd.Flags().GetBool("short"); short {
+	if cfg.Short {
 		testArgs = append(testArgs, "-short")
 	}
-	if failFast, _ := cmd.Flags().GetBool("failfast"); failFast {
+	if cfg.FailFast {
 		testArgs = append(testArgs, "-failfast")
 	}
-	if shuffle, _ := cmd.Flags().GetBool("shuffle"); shuffle {
+	if cfg.Shuffle {
 		testArgs = append(testArgs, "-shuffle", "on")
 	}
-	if !cfg.NoCover {
-		testArgs = append(testArgs, fmt.Sprintf("-coverprofile=%v", coverPath))
+	if cfg.Race {
+		testArgs = append(testArgs, "-race")
+	}
+	if cfg.Tags != "" {
+		testArgs = append(testArgs, "-tags="+cfg.Tags)
+	}
+	if cfg.Timeout > 0 {
+		testArgs = append(testArgs, "-timeout="+time.Duration(cfg.Timeout).String())
 	}
 	paths, tests := findPaths(args)
 	if len(tests) > 0 {
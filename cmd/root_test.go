@@ -3,8 +3,11 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -68,7 +71,13 @@ func TestFmtArgs(t *testing.T) {
 	t.Run("extended cfg flags", func(t *testing.T) {
 		args, err := fmtTestArgs(rootCmd, &Config{NoCover: false})
 		assert.Nil(t, err)
-		assert.Equal(t, []string{"go", "test", "-json", "-v", fmt.Sprintf("-coverprofile=%v", coverPath), "./..."}, args)
+		assert.Equal(t, []string{"go", "test", "-json", "-v", "./..."}, args)
+	})
+
+	t.Run("go test flags", func(t *testing.T) {
+		args, err := fmtTestArgs(rootCmd, &Config{Short: true, Race: true, Tags: "a,b", Timeout: Duration(time.Minute)})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"go", "test", "-json", "-v", "-short", "-race", "-tags=a,b", "-timeout=1m0s", "./..."}, args)
 	})
 }
 
@@ -126,3 +135,20 @@ func TestFindTestsInFile(t *testing.T) {
 		assert.Equal(t, testcase.tests, tests, fmt.Sprintf("testcase %v", i))
 	}
 }
+
+func TestReadCmd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.json")
+	events := `{"Action":"run","Package":"example.com/nope","Test":"TestA"}
+{"Action":"pass","Package":"example.com/nope","Test":"TestA","Elapsed":0.1}
+{"Action":"run","Package":"example.com/nope","Test":"TestB"}
+{"Action":"fail","Package":"example.com/nope","Test":"TestB","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/nope","Elapsed":0.2}
+`
+	assert.Nil(t, os.WriteFile(path, []byte(events), 0600))
+	code, err := readCmd(rootCmd, &Config{Display: "dots"}, path)
+	assert.Nil(t, err)
+	assert.Equal(t, exitTestFailure, code)
+
+	_, err = readCmd(rootCmd, &Config{Display: "dots"}, filepath.Join(t.TempDir(), "missing.json"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/tanema/og/lib/results"
+	"github.com/tanema/og/lib/shard"
+)
+
+// shardCommands plans which of the packages that args would test belong to this
+// shard and returns the go test commands that run them. Packages that run whole
+// share a single command, while each package that was split up by test needs
+// its own command so that -run only applies to it.
+func shardCommands(shardFlag, durationsPath string, args []string) ([][]string, *results.Shard, error) {
+	part, err := shard.Parse(shardFlag)
+	if err != nil {
+		return nil, nil, err
+	}
+	flags, paths := splitTestArgs(args)
+	pkgs, err := testPackages(paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	durations, err := shardDurations(durationsPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	hasRun := false
+	for _, flag := range flags {
+		hasRun = hasRun || flag == "-run"
+	}
+
+	info := &results.Shard{Index: part.Index, Total: part.Total, Tests: map[string][]string{}}
+	commands := [][]string{}
+	for _, unit := range part.Plan(pkgs, durations, !hasRun) {
+		if len(unit.Tests) == 0 {
+			info.Packages = append(info.Packages, unit.Package)
+			continue
+		}
+		info.Tests[unit.Package] = unit.Tests
+		run := fmt.Sprintf("^(%v)$", strings.Join(unit.Tests, "|"))
+		commands = append(commands, append(append(flags[:len(flags):len(flags)], "-run", run), unit.Package))
+	}
+	if len(info.Packages) > 0 {
+		commands = append([][]string{append(flags[:len(flags):len(flags)], info.Packages...)}, commands...)
+	}
+	return commands, info, nil
+}
+
+// splitTestArgs separates the go test command and its flags from the packages
+func splitTestArgs(args []string) (flags, paths []string) {
+	flags = args[:2:2]
+	for i := 2; i < len(args); i++ {
+		if (args[i] == "-run" || args[i] == "-shuffle") && i+1 < len(args) {
+			flags = append(flags, args[i], args[i+1])
+			i++
+		} else if strings.HasPrefix(args[i], "-") {
+			flags = append(flags, args[i])
+		} else {
+			paths = append(paths, args[i])
+		}
+	}
+	return flags, paths
+}
+
+// testPackages finds the packages that have tests and the top level tests in
+// each of them. Packages with a test file that cannot be read have no tests
+// listed so that they are never split up by test.
+func testPackages(paths []string) ([]shard.Package, error) {
+	loaded, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedFiles, Tests: true}, paths...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find packages to shard: %v", err)
+	}
+	tests := map[string]map[string]bool{}
+	unlisted := map[string]bool{}
+	for _, pkg := range loaded {
+		pkgPath := strings.TrimSuffix(pkg.PkgPath, "_test")
+		for _, file := range pkg.GoFiles {
+			if !strings.HasSuffix(file, "_test.go") {
+				continue
+			}
+			if _, ok := tests[pkgPath]; !ok {
+				tests[pkgPath] = map[string]bool{}
+			}
+			names, err := testFuncs(file)
+			if err != nil {
+				unlisted[pkgPath] = true
+			}
+			for _, name := range names {
+				tests[pkgPath][name] = true
+			}
+		}
+	}
+	pkgs := []shard.Package{}
+	for pkgPath, names := range tests {
+		pkg := shard.Package{Path: pkgPath}
+		for name := range names {
+			pkg.Tests = append(pkg.Tests, name)
+		}
+		if unlisted[pkgPath] {
+			pkg.Tests = nil
+		}
+		sort.Strings(pkg.Tests)
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Path < pkgs[j].Path })
+	return pkgs, nil
+}
+
+// testFuncs finds the Test, Fuzz and Example functions in a test file, which
+// are everything that -run selects.
+func testFuncs(file string) ([]string, error) {
+	parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Name.Name == "TestMain" {
+			continue
+		}
+		for _, prefix := range []string{"Test", "Fuzz", "Example"} {
+			if isTestFunc(fn.Name.Name, prefix) {
+				names = append(names, fn.Name.Name)
+				break
+			}
+		}
+	}
+	return names, nil
+}
+
+// isTestFunc checks a name the way go test does, the prefix has to be followed
+// by nothing or by something that is not a lower case letter.
+func isTestFunc(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	} else if len(name) == len(prefix) {
+		return true
+	}
+	next, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(next)
+}
+
+// shardDurations reads how long packages and tests took from a dump if one was
+// given. The history is never used since every machine has its own, and they
+// all have to plan the same shards, so without a dump packages are split by hash.
+func shardDurations(path string) (*shard.Durations, error) {
+	durations := shard.NewDurations()
+	if path == "" {
+		return durations, nil
+	}
+	set, err := loadReport(path)
+	if err != nil {
+		return nil, err
+	}
+	addDurations(durations, set)
+	return durations, nil
+}
+
+// addDurations adds the times from a set, cached packages are left out since
+// they did not really run. Packages that were split up by a shard, or that ran
+// with a test filter, only ran some of their tests so only the test times are
+// used.
+func addDurations(durations *shard.Durations, set *results.Set) {
+	for name, pkg := range set.Packages {
+		if pkg.Cached || pkg.State == results.Skip {
+			continue
+		}
+		if set.Filter == "" && (set.Shard == nil || set.Shard.Tests[name] == nil) {
+			durations.Add(name, "", pkg.Elapsed())
+		}
+		for testName, test := range pkg.Tests {
+			if !strings.Contains(testName, "/") {
+				durations.Add(name, testName, test.Elapsed())
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tanema/og/lib/results"
+	"github.com/tanema/og/lib/shard"
+)
+
+func TestSplitTestArgs(t *testing.T) {
+	flags, paths := splitTestArgs([]string{"go", "test", "-json", "-v", "-shuffle", "on", "-run", "TestA", "./a", "./b"})
+	assert.Equal(t, []string{"go", "test", "-json", "-v", "-shuffle", "on", "-run", "TestA"}, flags)
+	assert.Equal(t, []string{"./a", "./b"}, paths)
+}
+
+func TestTestPackages(t *testing.T) {
+	pkgs, err := testPackages([]string{"../lib/shard"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pkgs))
+	assert.Equal(t, "github.com/tanema/og/lib/shard", pkgs[0].Path)
+	assert.Contains(t, pkgs[0].Tests, "TestPlan")
+}
+
+func TestTestFuncs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a_test.go")
+	assert.Nil(t, os.WriteFile(path, []byte(`package a
+
+import "testing"
+
+func TestMain(m *testing.M) {}
+func TestA(tt *testing.T)    {}
+func Test(t *testing.T)      {}
+func Testify(t *testing.T)   {}
+func FuzzA(f *testing.F)     {}
+func ExampleA()              {}
+func BenchmarkA(b *testing.B) {}
+func (s suite) TestB(t *testing.T) {}
+`), 0644))
+	names, err := testFuncs(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"TestA", "Test", "FuzzA", "ExampleA"}, names)
+
+	assert.Nil(t, os.WriteFile(path, []byte("package a\nfunc TestA(\n"), 0644))
+	_, err = testFuncs(path)
+	assert.NotNil(t, err)
+}
+
+func TestAddDurations(t *testing.T) {
+	set := results.New("", time.Minute)
+	set.Add(results.Run, "a", "TestA", "")
+	set.Add(results.Pass, "a", "TestA", "")
+	set.Add(results.Pass, "a", "", "")
+	set.Add(results.Pass, "b", "TestB", "")
+	set.Add(results.Pass, "b", "", "")
+	set.Add(results.Skip, "c", "", "")
+	set.Shard = &results.Shard{Index: 1, Total: 2, Tests: map[string][]string{"b": {"TestB"}}}
+
+	durations := shard.NewDurations()
+	addDurations(durations, set)
+	assert.Contains(t, durations.Packages, "a")
+	assert.NotContains(t, durations.Packages, "b")
+	assert.NotContains(t, durations.Packages, "c")
+	assert.Contains(t, durations.Tests["a"], "TestA")
+	assert.Contains(t, durations.Tests["b"], "TestB")
+
+	set.Shard, set.Filter = nil, "TestA"
+	durations = shard.NewDurations()
+	addDurations(durations, set)
+	assert.Empty(t, durations.Packages)
+	assert.Contains(t, durations.Tests["a"], "TestA")
+
+	durations, err := shardDurations("")
+	assert.Nil(t, err)
+	assert.Empty(t, durations.Packages)
+	assert.Empty(t, durations.Tests)
+}
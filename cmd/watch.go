@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"context"
+	_ "embed" // to allow embedding strings
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tanema/og/lib/results"
+	"github.com/tanema/og/lib/term"
+	"github.com/tanema/og/lib/watch"
+)
+
+//go:embed templates/watch.tmpl
+var watchtmpl string
+
+// watchDebounce is how long to wait for more file changes before running, so
+// that saving many files at once only runs the tests once.
+const watchDebounce = 200 * time.Millisecond
+
+type (
+	// watchSession is the state of og -w between runs
+	watchSession struct {
+		cmd      *cobra.Command
+		cfg      *Config
+		target   []string
+		lastArgs []string
+		lastSet  *results.Set
+		keyboard *term.Keyboard
+		polling  bool
+		cancel   context.CancelFunc
+		finished chan watchRun
+	}
+	// watchRun is the outcome of a run in the background
+	watchRun struct {
+		args []string
+		set  *results.Set
+		err  error
+	}
+)
+
+// watchTestChanges reruns tests when files change. Changes are batched and only
+// the changed packages that the target from the command line covers are run,
+// with the same -run filter. Changes outside of the target run all of it since
+// they could be dependencies. A run that is still going when new changes or keys
+// arrive is killed. If stdin is a terminal it is put into raw mode so that runs
+// can also be started with keys.
+func watchTestChanges(cmd *cobra.Command, cfg *Config, args []string, set *results.Set) error {
+	session := &watchSession{cmd: cmd, cfg: cfg, target: args, lastArgs: args, lastSet: set, finished: make(chan watchRun, 1)}
+	defer session.stop()
+	watcher, err := watch.New(cfg.WatchIgnore, cfg.Poll)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	session.polling = watcher.Polling
+	go watcher.Start()
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+	var keys chan term.Key
+	if keyboard, err := term.OpenKeyboard(os.Stdin); err == nil {
+		defer keyboard.Close()
+		session.keyboard = keyboard
+		keys = keyboard.Keys
+	}
+	if err := session.help(); err != nil {
+		return err
+	}
+	changed := map[string]bool{}
+	var debounce <-chan time.Time
+	for {
+		select {
+		case path, ok := <-watcher.Changes:
+			if !ok {
+				return nil
+			}
+			changed[changedDir(path)] = true
+			debounce = time.After(watchDebounce)
+		case <-debounce:
+			args := changeArgs(session.target, sortedKeys(changed))
+			changed, debounce = map[string]bool{}, nil
+			if err := session.start(args); err != nil {
+				return err
+			}
+		case run := <-session.finished:
+			if err := session.finish(run); err != nil {
+				return err
+			}
+		case key, ok := <-keys:
+			if !ok {
+				keys = nil
+				continue
+			}
+			if quit, err := session.onKey(key); quit || err != nil {
+				return err
+			}
+		case err := <-watcher.Fallback:
+			session.polling = true
+			if err := session.message(fmt.Sprintf("Polling for changes, file system events failed: %v", err)); err != nil {
+				return err
+			}
+		case err := <-watcher.Errors:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// onKey handles a single key press, returning true if watching should stop
+func (session *watchSession) onKey(key term.Key) (bool, error) {
+	switch key {
+	case term.KeyEnter:
+		return false, session.start(session.lastArgs)
+	case "a":
+		args, err := fmtTestArgs(session.cmd, session.cfg)
+		if err != nil {
+			return false, err
+		}
+		return false, session.start(args)
+	case "f":
+		args, err := session.failedArgs()
+		if err != nil {
+			return false, err
+		} else if args == nil {
+			return false, session.message("No failed tests to run")
+		}
+		return false, session.start(args)
+	case "p":
+		if err := session.stop(); err != nil {
+			return false, err
+		}
+		filter, ok := session.prompt()
+		if !ok {
+			return false, session.help()
+		}
+		args, err := fmtTestArgs(session.cmd, session.cfg, strings.Fields(filter)...)
+		if err != nil {
+			return false, err
+		}
+		return false, session.start(args)
+	case "c":
+		session.cfg.NoCover = !session.cfg.NoCover
+		return false, session.help()
+	case "q", term.KeyCtrlC:
+		return true, nil
+	}
+	return false, nil
+}
+
+// start runs the tests for args in the background, killing the run before it
+func (session *watchSession) start(args []string) error {
+	if err := session.stop(); err != nil {
+		return err
+	}
+	err := term.PrintlnTmpl("watch_running", struct{ Time, Target string }{Time: now(), Target: runTarget(args)}, summarytmpl, watchtmpl)
+	if err != nil {
+		return err
+	}
+	// the config is copied so that it can be changed by keys during the run
+	runCfg := *session.cfg
+	ctx, cancel := context.WithCancel(session.cmd.Context())
+	session.cancel = cancel
+	go func() {
+		set, _, err := runCmd(ctx, session.cmd, &runCfg, args...)
+		session.finished <- watchRun{args: args, set: set, err: err}
+	}()
+	return nil
+}
+
+// stop kills the run in the background if there is one and waits for it
+func (session *watchSession) stop() error {
+	if session.cancel == nil {
+		return nil
+	}
+	session.cancel()
+	return session.finish(<-session.finished)
+}
+
+// finish remembers the args and results of a run for the next rerun
+func (session *watchSession) finish(run watchRun) error {
+	session.cancel()
+	session.cancel = nil
+	if errors.Is(run.err, context.Canceled) {
+		return nil
+	} else if run.err != nil {
+		return run.err
+	}
+	session.lastArgs, session.lastSet = run.args, run.set
+	return session.help()
+}
+
+// failedArgs runs the top level tests that failed in the last run, or nil if
+// none failed.
+func (session *watchSession) failedArgs() ([]string, error) {
+	if session.lastSet == nil || len(session.lastSet.FailedTests) == 0 {
+		return nil, nil
+	}
+	names, pkgs := map[string]bool{}, map[string]bool{}
+	for _, test := range session.lastSet.FailedTests {
+		names[regexp.QuoteMeta(strings.Split(test.Name, "/")[0])] = true
+		pkgs[test.Package] = true
+	}
+	base, err := fmtTestArgs(session.cmd, session.cfg)
+	if err != nil {
+		return nil, err
+	}
+	flags, _ := splitTestArgs(base)
+	args := append(flags, "-run", fmt.Sprintf("^(%v)$", strings.Join(sortedKeys(names), "|")))
+	return append(args, sortedKeys(pkgs)...), nil
+}
+
+// prompt reads a line typed by the user, returning false if it was cancelled
+func (session *watchSession) prompt() (string, bool) {
+	screen := term.NewScreenBuf(os.Stderr, summarytmpl, watchtmpl)
+	input := ""
+	for {
+		if err := screen.RenderTmpl("watch_prompt", input); err != nil {
+			return "", false
+		}
+		key, ok := <-session.keyboard.Keys
+		switch {
+		case !ok || key == term.KeyEsc || key == term.KeyCtrlC:
+			return "", false
+		case key == term.KeyEnter:
+			return input, true
+		case key == term.KeyBack:
+			if runes := []rune(input); len(runes) > 0 {
+				input = string(runes[:len(runes)-1])
+			}
+		default:
+			if runes := []rune(string(key)); len(runes) == 1 && unicode.IsPrint(runes[0]) {
+				input += string(key)
+			}
+		}
+	}
+}
+
+func (session *watchSession) message(msg string) error {
+	if err := term.PrintlnTmpl("watch_message", msg, summarytmpl, watchtmpl); err != nil {
+		return err
+	}
+	return session.help()
+}
+
+func (session *watchSession) help() error {
+	data := struct {
+		Cfg     *Config
+		Keys    bool
+		Polling bool
+	}{Cfg: session.cfg, Keys: session.keyboard != nil, Polling: session.polling}
+	return term.PrintlnTmpl("watch_help", data, summarytmpl, watchtmpl)
+}
+
+// changeArgs keeps the flags and -run filter of the target but only tests the
+// changed package directories that it covers, or all of the target if it
+// covers none of them or everything changed.
+func changeArgs(target, dirs []string) []string {
+	flags, paths := splitTestArgs(target)
+	covered := []string{}
+	for _, dir := range dirs {
+		if dir == watch.Everything {
+			return target
+		}
+		for _, path := range paths {
+			if coversDir(path, dir) {
+				covered = append(covered, dir)
+				break
+			}
+		}
+	}
+	if len(covered) == 0 {
+		return target
+	}
+	return append(flags, covered...)
+}
+
+// coversDir checks if a package path like ./lib/... includes the directory
+func coversDir(path, dir string) bool {
+	dir = filepath.Clean(dir)
+	if base := strings.TrimSuffix(path, "/..."); base != path {
+		base = filepath.Clean(base)
+		return base == "." || dir == base || strings.HasPrefix(dir, base+"/")
+	}
+	return dir == filepath.Clean(path)
+}
+
+// changedDir is the package directory of a changed path relative to the root
+func changedDir(path string) string {
+	if strings.HasSuffix(path, ".go") {
+		path = filepath.Dir(path)
+	}
+	if rel, err := filepath.Rel(root, path); err == nil && filepath.IsAbs(path) {
+		path = rel
+	}
+	if path = filepath.ToSlash(filepath.Clean(path)); path == "." || strings.HasPrefix(path, "../") {
+		return path
+	}
+	return "./" + path
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := []string{}
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func now() string {
+	current := time.Now()
+	return fmt.Sprintf("%02d:%02d:%02d", current.Hour(), current.Minute(), current.Second())
+}
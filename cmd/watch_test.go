@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tanema/og/lib/results"
+	"github.com/tanema/og/lib/watch"
+)
+
+func TestFailedArgs(t *testing.T) {
+	session := &watchSession{cmd: &cobra.Command{}, cfg: &Config{}}
+	args, err := session.failedArgs()
+	assert.Nil(t, err)
+	assert.Nil(t, args)
+
+	session.lastSet = results.New("./...", 0)
+	session.lastSet.FailedTests = []*results.Test{
+		{Name: "TestB/sub", Package: "example.com/b"},
+		{Name: "TestA", Package: "example.com/a"},
+		{Name: "TestB/other", Package: "example.com/b"},
+	}
+	args, err = session.failedArgs()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"-run", "^(TestA|TestB)$", "example.com/a", "example.com/b"}, args[len(args)-4:])
+}
+
+func TestChangeArgs(t *testing.T) {
+	target := []string{"go", "test", "-json", "-v", "-run", "TestA", "./lib/...", "./cmd"}
+	assert.Equal(t, []string{"go", "test", "-json", "-v", "-run", "TestA", "./lib/term", "./cmd"}, changeArgs(target, []string{"./lib/term", "./cmd", "./other"}))
+	assert.Equal(t, target, changeArgs(target, []string{"./other"}))
+	assert.Equal(t, target, changeArgs(target, []string{"./lib/term", watch.Everything}))
+	assert.Equal(t, []string{"go", "test", "./lib"}, changeArgs([]string{"go", "test", "./..."}, []string{"./lib"}))
+}
+
+func TestChangedDir(t *testing.T) {
+	assert.Equal(t, "./lib/term", changedDir("lib/term/keys_test.go"))
+	assert.Equal(t, "./lib/term", changedDir("lib/term"))
+	assert.Equal(t, ".", changedDir("main.go"))
+	assert.Equal(t, "./cmd", changedDir(filepath.Join(root, "cmd", "watch.go")))
+}
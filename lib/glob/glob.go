@@ -0,0 +1,113 @@
+package glob
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Pattern is a compiled glob pattern. It supports the same syntax as
+// filepath.Match as well as ** to match across directories. A pattern without
+// a slash will match against the base name of a path so that *.pb.go matches
+// files in any directory.
+type Pattern struct {
+	src      string
+	basename bool
+	regx     *regexp.Regexp
+}
+
+// Compile will parse a glob pattern into a Pattern
+func Compile(src string) (*Pattern, error) {
+	cleaned := strings.TrimPrefix(filepath.ToSlash(src), "./")
+	basename := !strings.Contains(strings.TrimSuffix(cleaned, "/"), "/")
+	cleaned = strings.TrimPrefix(cleaned, "/")
+	if basename {
+		cleaned = strings.TrimSuffix(cleaned, "/")
+	}
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(cleaned); i++ {
+		switch ch := cleaned[i]; ch {
+		case '*':
+			if strings.HasPrefix(cleaned[i:], "**/") {
+				expr.WriteString("(.*/)?")
+				i += 2
+			} else if strings.HasPrefix(cleaned[i:], "**") {
+				expr.WriteString(".*")
+				i++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(cleaned[i:], ']')
+			if end < 0 {
+				expr.WriteString(regexp.QuoteMeta(string(ch)))
+				continue
+			}
+			class := cleaned[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	if strings.HasSuffix(cleaned, "/") {
+		expr.WriteString(".*")
+	} else {
+		expr.WriteString("(/.*)?")
+	}
+	expr.WriteString("$")
+	regx, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{src: src, basename: basename, regx: regx}, nil
+}
+
+// CompileAll compiles a list of patterns, stopping at the first error
+func CompileAll(srcs []string) ([]*Pattern, error) {
+	patterns := make([]*Pattern, 0, len(srcs))
+	for _, src := range srcs {
+		pattern, err := Compile(src)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// Match will check if the path, or one of its parent directories, matches the
+// pattern.
+func (pattern *Pattern) Match(path string) bool {
+	path = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "./")
+	if !pattern.basename {
+		return pattern.regx.MatchString(path)
+	}
+	for _, part := range strings.Split(path, "/") {
+		if pattern.regx.MatchString(part) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the source of the pattern
+func (pattern *Pattern) String() string {
+	return pattern.src
+}
+
+// Any will check if any of the patterns match the path
+func Any(patterns []*Pattern, path string) bool {
+	for _, pattern := range patterns {
+		if pattern.Match(path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package glob
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{pattern: "*.pb.go", path: "lib/proto/user.pb.go", match: true},
+		{pattern: "*.pb.go", path: "./user.pb.go", match: true},
+		{pattern: "*.pb.go", path: "lib/proto/user.go", match: false},
+		{pattern: "mocks", path: "lib/mocks/user.go", match: true},
+		{pattern: "mocks/", path: "lib/mocks/user.go", match: true},
+		{pattern: "vendor", path: "lib/vendorish/user.go", match: false},
+		{pattern: "lib/*.go", path: "lib/user.go", match: true},
+		{pattern: "lib/*.go", path: "lib/pack/user.go", match: false},
+		{pattern: "lib/**/*.go", path: "lib/pack/user.go", match: true},
+		{pattern: "lib/**/*.go", path: "lib/user.go", match: true},
+		{pattern: "**/mock_*.go", path: "a/b/mock_user.go", match: true},
+		{pattern: "./lib/gen", path: "lib/gen/user.go", match: true},
+		{pattern: "/lib/gen", path: "lib/gen/user.go", match: true},
+		{pattern: "user_?.go", path: "user_a.go", match: true},
+		{pattern: "user_[ab].go", path: "user_b.go", match: true},
+		{pattern: "user_[!ab].go", path: "user_b.go", match: false},
+		{pattern: "github.com/tanema/og/lib/gen/*", path: "github.com/tanema/og/lib/gen/x.go", match: true},
+	}
+	for i, testcase := range cases {
+		pattern, err := Compile(testcase.pattern)
+		assert.Nil(t, err)
+		assert.Equal(t, testcase.match, pattern.Match(testcase.path), fmt.Sprintf("testcase %v: %v %v", i, testcase.pattern, testcase.path))
+	}
+}
+
+func TestAny(t *testing.T) {
+	patterns, err := CompileAll([]string{"*.pb.go", "mocks"})
+	assert.Nil(t, err)
+	assert.True(t, Any(patterns, "lib/mocks/user.go"))
+	assert.True(t, Any(patterns, "lib/user.pb.go"))
+	assert.False(t, Any(patterns, "lib/user.go"))
+	assert.False(t, Any(nil, "lib/user.go"))
+}
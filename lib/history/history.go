@@ -0,0 +1,142 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tanema/og/lib/results"
+)
+
+// MaxRuns is the amount of runs kept in a store before the oldest are removed
+const MaxRuns = 100
+
+type (
+	// Run is a single recorded run of og
+	Run struct {
+		ID        string       `json:"id"`
+		Time      time.Time    `json:"time"`
+		GoVersion string       `json:"go_version,omitempty"`
+		GitSHA    string       `json:"git_sha,omitempty"`
+		Target    string       `json:"target"`
+		Flags     []string     `json:"flags,omitempty"`
+		Set       *results.Set `json:"set"`
+	}
+	// Store is a directory of recorded runs for a single project
+	Store struct {
+		dir string
+	}
+)
+
+// Open will open the store at dir, creating it if needed
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create history store: %v", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save records a run in the store, removing the oldest runs once the store has
+// more than MaxRuns.
+func (store *Store) Save(run *Run) error {
+	if run.Time.IsZero() {
+		run.Time = time.Now()
+	}
+	if run.ID == "" {
+		run.ID = strconv.FormatInt(run.Time.UnixNano(), 10)
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(store.dir, run.ID+".json"), data, 0600); err != nil {
+		return fmt.Errorf("cannot save run: %v", err)
+	}
+	return store.prune()
+}
+
+// List returns all of the stored runs, the most recent first
+func (store *Store) List() ([]*Run, error) {
+	ids, err := store.ids()
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]*Run, 0, len(ids))
+	for _, id := range ids {
+		run, err := store.Load(id)
+		if err != nil {
+			continue // skip runs that were only partially written
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// Last finds the most recent run of the same target, it returns nil if the
+// target has never been run before.
+func (store *Store) Last(target string) (*Run, error) {
+	ids, err := store.ids()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if run, err := store.Load(id); err == nil && run.Target == target {
+			return run, nil
+		}
+	}
+	return nil, nil
+}
+
+// Load will load a single run by its id
+func (store *Store) Load(id string) (*Run, error) {
+	data, err := os.ReadFile(filepath.Join(store.dir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	run := &Run{}
+	if err := json.Unmarshal(data, run); err != nil {
+		return nil, fmt.Errorf("cannot read run %v: %v", id, err)
+	}
+	return run, nil
+}
+
+// ids lists the ids of all stored runs, the most recent first
+func (store *Store) ids() ([]string, error) {
+	entries, err := os.ReadDir(store.dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read history store: %v", err)
+	}
+	ids := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return runOrder(ids[i]) > runOrder(ids[j])
+	})
+	return ids, nil
+}
+
+func (store *Store) prune() error {
+	ids, err := store.ids()
+	if err != nil {
+		return err
+	}
+	for i := MaxRuns; i < len(ids); i++ {
+		if err := os.Remove(filepath.Join(store.dir, ids[i]+".json")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runOrder(id string) int64 {
+	order, _ := strconv.ParseInt(id, 10, 64)
+	return order
+}
@@ -0,0 +1,85 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tanema/og/lib/results"
+)
+
+func newSet(state results.Action) *results.Set {
+	set := results.New("", time.Minute)
+	set.Add(results.Run, "github.com/tanema/og/nope", "TestA", "")
+	set.Add(state, "github.com/tanema/og/nope", "TestA", "")
+	set.Add(state, "github.com/tanema/og/nope", "", "")
+	set.Complete(false, "")
+	return set
+}
+
+func TestStore(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "history"))
+	assert.Nil(t, err)
+
+	runs, err := store.List()
+	assert.Nil(t, err)
+	assert.Empty(t, runs)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		run := &Run{Time: start.Add(time.Duration(i) * time.Second), Target: "./...", GitSHA: fmt.Sprintf("sha%v", i), Set: newSet(results.Pass)}
+		assert.Nil(t, store.Save(run))
+		assert.NotEmpty(t, run.ID)
+	}
+	assert.Nil(t, os.WriteFile(filepath.Join(store.dir, "broken.json"), []byte("{"), 0600))
+
+	runs, err = store.List()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(runs))
+	assert.Equal(t, "sha2", runs[0].GitSHA)
+	assert.Equal(t, "sha0", runs[2].GitSHA)
+	assert.Equal(t, results.Pass, runs[0].Set.Packages["github.com/tanema/og/nope"].Tests["TestA"].State)
+
+	run, err := store.Load(runs[1].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "sha1", run.GitSHA)
+}
+
+func TestStorePrune(t *testing.T) {
+	store, err := Open(t.TempDir())
+	assert.Nil(t, err)
+	start := time.Now()
+	set := newSet(results.Pass)
+	for i := 0; i < MaxRuns+2; i++ {
+		assert.Nil(t, store.Save(&Run{Time: start.Add(time.Duration(i) * time.Second), GitSHA: fmt.Sprintf("sha%v", i), Set: set}))
+	}
+	ids, err := store.ids()
+	assert.Nil(t, err)
+	assert.Equal(t, MaxRuns, len(ids))
+	run, err := store.Load(ids[len(ids)-1])
+	assert.Nil(t, err)
+	assert.Equal(t, "sha2", run.GitSHA)
+}
+
+func TestStoreLast(t *testing.T) {
+	store, err := Open(t.TempDir())
+	assert.Nil(t, err)
+	run, err := store.Last("./...")
+	assert.Nil(t, err)
+	assert.Nil(t, run)
+
+	start := time.Now()
+	assert.Nil(t, store.Save(&Run{Time: start, Target: "./...", GitSHA: "all", Set: newSet(results.Pass)}))
+	assert.Nil(t, store.Save(&Run{Time: start.Add(time.Second), Target: "./lib", GitSHA: "lib", Set: newSet(results.Pass)}))
+
+	run, err = store.Last("./...")
+	assert.Nil(t, err)
+	assert.Equal(t, "all", run.GitSHA)
+	run, err = store.Last("./lib")
+	assert.Nil(t, err)
+	assert.Equal(t, "lib", run.GitSHA)
+}
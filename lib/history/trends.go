@@ -0,0 +1,154 @@
+package history
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tanema/og/lib/results"
+)
+
+var sparkGlyphs = []rune("▁▂▃▄▅▆▇█")
+
+type (
+	// TestTrend is the history of a single test across runs, oldest first
+	TestTrend struct {
+		Package   string           `json:"package"`
+		Name      string           `json:"name"`
+		Runs      int              `json:"runs"`
+		Failures  int              `json:"failures"`
+		States    []results.Action `json:"states"`
+		Durations []time.Duration  `json:"durations"`
+	}
+	// CoverageTrend is the coverage of a package across runs, oldest first
+	CoverageTrend struct {
+		Package  string    `json:"package"`
+		Percents []float64 `json:"percents"`
+	}
+)
+
+// TestTrends collects the state and duration of every test in the runs. Runs
+// are expected most recent first, like they are returned from List.
+func TestTrends(runs []*Run, filter string) []*TestTrend {
+	trends := map[string]*TestTrend{}
+	for i := len(runs) - 1; i >= 0; i-- {
+		for _, pkg := range runs[i].Set.Packages {
+			for _, test := range pkg.Tests {
+				if filter != "" && !strings.Contains(test.Package+"#"+test.Name, filter) {
+					continue
+				}
+				key := test.Package + "#" + test.Name
+				if _, ok := trends[key]; !ok {
+					trends[key] = &TestTrend{Package: test.Package, Name: test.Name}
+				}
+				trend := trends[key]
+				trend.Runs++
+				if test.State == results.Fail {
+					trend.Failures++
+				}
+				trend.States = append(trend.States, test.State)
+				trend.Durations = append(trend.Durations, test.Elapsed())
+			}
+		}
+	}
+	list := make([]*TestTrend, 0, len(trends))
+	for _, trend := range trends {
+		list = append(list, trend)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Failures != list[j].Failures {
+			return list[i].Failures > list[j].Failures
+		} else if list[i].Package != list[j].Package {
+			return list[i].Package < list[j].Package
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// CoverageTrends collects the coverage of every package in the runs. Runs are
+// expected most recent first, like they are returned from List. The overall
+// coverage is reported with an empty package name.
+func CoverageTrends(runs []*Run) []*CoverageTrend {
+	trends := map[string]*CoverageTrend{"": {}}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Set.StatementCount == 0 {
+			continue
+		}
+		trends[""].Percents = append(trends[""].Percents, runs[i].Set.CoveragePercent)
+		for name, pkg := range runs[i].Set.Packages {
+			if pkg.StatementCount == 0 {
+				continue
+			}
+			if _, ok := trends[name]; !ok {
+				trends[name] = &CoverageTrend{Package: name}
+			}
+			trends[name].Percents = append(trends[name].Percents, pkg.CoveragePercent)
+		}
+	}
+	list := make([]*CoverageTrend, 0, len(trends))
+	for _, trend := range trends {
+		if len(trend.Percents) > 0 {
+			list = append(list, trend)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Package < list[j].Package })
+	return list
+}
+
+// Last is the duration of the most recent run of the test
+func (trend *TestTrend) Last() time.Duration {
+	return trend.Durations[len(trend.Durations)-1]
+}
+
+// Average is the mean duration of the test across all runs
+func (trend *TestTrend) Average() time.Duration {
+	var total time.Duration
+	for _, duration := range trend.Durations {
+		total += duration
+	}
+	return (total / time.Duration(len(trend.Durations))).Round(time.Millisecond)
+}
+
+// Spark renders the durations as a sparkline
+func (trend *TestTrend) Spark() string {
+	values := make([]float64, len(trend.Durations))
+	for i, duration := range trend.Durations {
+		values[i] = float64(duration)
+	}
+	return spark(values)
+}
+
+// Last is the most recent coverage of the package
+func (trend *CoverageTrend) Last() float64 {
+	return trend.Percents[len(trend.Percents)-1]
+}
+
+// Spark renders the coverage percentages as a sparkline
+func (trend *CoverageTrend) Spark() string {
+	return spark(trend.Percents)
+}
+
+func spark(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	low, high := values[0], values[0]
+	for _, val := range values {
+		if val < low {
+			low = val
+		}
+		if val > high {
+			high = val
+		}
+	}
+	var out strings.Builder
+	for _, val := range values {
+		index := 0
+		if high > low {
+			index = int((val - low) / (high - low) * float64(len(sparkGlyphs)-1))
+		}
+		out.WriteRune(sparkGlyphs[index])
+	}
+	return out.String()
+}
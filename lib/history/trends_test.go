@@ -0,0 +1,53 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tanema/og/lib/results"
+)
+
+func TestTestTrends(t *testing.T) {
+	runs := []*Run{{Set: newSet(results.Fail)}, {Set: newSet(results.Pass)}, {Set: newSet(results.Fail)}}
+	trends := TestTrends(runs, "")
+	assert.Equal(t, 1, len(trends))
+	assert.Equal(t, "TestA", trends[0].Name)
+	assert.Equal(t, 3, trends[0].Runs)
+	assert.Equal(t, 2, trends[0].Failures)
+	assert.Equal(t, []results.Action{results.Fail, results.Pass, results.Fail}, trends[0].States)
+	assert.Equal(t, 3, len(trends[0].Durations))
+
+	assert.Empty(t, TestTrends(runs, "TestB"))
+	assert.Equal(t, 1, len(TestTrends(runs, "nope#TestA")))
+}
+
+func TestCoverageTrends(t *testing.T) {
+	older, newer := newSet(results.Pass), newSet(results.Pass)
+	older.StatementCount, older.CoveragePercent = 10, 50
+	newer.StatementCount, newer.CoveragePercent = 10, 75
+	newer.Packages["github.com/tanema/og/nope"].StatementCount = 10
+	newer.Packages["github.com/tanema/og/nope"].CoveragePercent = 75
+
+	trends := CoverageTrends([]*Run{{Set: newer}, {Set: older}, {Set: newSet(results.Pass)}})
+	assert.Equal(t, 2, len(trends))
+	assert.Equal(t, "", trends[0].Package)
+	assert.Equal(t, []float64{50, 75}, trends[0].Percents)
+	assert.Equal(t, 75.0, trends[0].Last())
+	assert.Equal(t, "github.com/tanema/og/nope", trends[1].Package)
+	assert.Equal(t, []float64{75}, trends[1].Percents)
+}
+
+func TestTrendStats(t *testing.T) {
+	trend := &TestTrend{Durations: []time.Duration{time.Second, 3 * time.Second, 2 * time.Second}}
+	assert.Equal(t, 2*time.Second, trend.Last())
+	assert.Equal(t, 2*time.Second, trend.Average())
+	assert.Equal(t, "▁█▄", trend.Spark())
+}
+
+func TestSpark(t *testing.T) {
+	assert.Equal(t, "", spark(nil))
+	assert.Equal(t, "▁▁", spark([]float64{5, 5}))
+	assert.Equal(t, "▁▄█", spark([]float64{0, 50, 100}))
+}
@@ -0,0 +1,293 @@
+package results
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/cover"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/tanema/og/lib/glob"
+)
+
+const noCoverDirective = "//og:nocover"
+
+var generatedPattern = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+type (
+	// coverFile is a single file from a cover profile with its blocks split up
+	// into the blocks that count towards coverage and the ones that are excluded
+	coverFile struct {
+		*cover.Profile
+		Path     string
+		Included []cover.ProfileBlock
+		Excluded []cover.ProfileBlock
+		fset     *token.FileSet
+		ast      *ast.File
+		src      []byte
+	}
+	// coverRegion is a range of source marked with a nocover directive
+	coverRegion struct {
+		start, end token.Position
+	}
+)
+
+// parseCoverProfile reads a cover profile and attributes each block to the
+// package that owns the file. Blocks that were reported by more than one test
+// binary, as happens with -coverpkg, are merged so they are only counted once.
+// A missing profile is not an error since no test binary may have written one.
+func (set *Set) parseCoverProfile(coverPath string, excludes []string) error {
+	files, err := loadCoverProfile(coverPath, excludes)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, file := range files {
+		pkg := set.coverPackage(path.Dir(file.FileName))
+		for _, block := range file.Included {
+			pkg.CoverBlocks = append(pkg.CoverBlocks, newCoverBlock(file.FileName, block, false))
+			stmts := int64(block.NumStmt)
+			pkg.StatementCount += stmts
+			set.StatementCount += stmts
+			if block.Count > 0 {
+				pkg.CoveredCount += stmts
+				set.CoveredCount += stmts
+			}
+		}
+		for _, block := range file.Excluded {
+			pkg.CoverBlocks = append(pkg.CoverBlocks, newCoverBlock(file.FileName, block, true))
+			pkg.ExcludedCount += int64(block.NumStmt)
+			set.ExcludedCount += int64(block.NumStmt)
+		}
+		pkg.CoveragePercent = calcPercent(pkg.StatementCount, pkg.CoveredCount)
+	}
+	set.CoveragePercent = calcPercent(set.StatementCount, set.CoveredCount)
+	return nil
+}
+
+func newCoverBlock(file string, block cover.ProfileBlock, excluded bool) *CoverBlock {
+	return &CoverBlock{
+		File:      file,
+		StartLine: block.StartLine,
+		StartCol:  block.StartCol,
+		EndLine:   block.EndLine,
+		EndCol:    block.EndCol,
+		NumStmt:   block.NumStmt,
+		Count:     block.Count,
+		Excluded:  excluded,
+	}
+}
+
+// coverPackage finds the package to attribute coverage to. Packages that were
+// only covered by other packages tests will not have any events so they are
+// added as packages without tests.
+func (set *Set) coverPackage(name string) *Package {
+	if pkg, ok := set.Packages[name]; ok {
+		return pkg
+	}
+	pkg := &Package{
+		stopwatch: &stopwatch{},
+		Name:      name,
+		State:     Skip,
+		Tests:     map[string]*Test{},
+	}
+	set.Packages[name] = pkg
+	return pkg
+}
+
+// loadCoverProfile parses a cover profile and splits out the blocks that should
+// not count towards coverage. Generated files, files matching one of the
+// exclude globs and regions marked with //og:nocover are all excluded.
+func loadCoverProfile(coverPath string, excludes []string) ([]*coverFile, error) {
+	patterns, err := glob.CompileAll(excludes)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := cover.ParseProfiles(coverPath)
+	if err != nil {
+		return nil, err
+	}
+	localPaths, modulePaths := localFilePaths(profiles)
+	files := make([]*coverFile, 0, len(profiles))
+	for _, profile := range profiles {
+		file := &coverFile{Profile: profile, Path: localPaths[profile.FileName]}
+		// excludes are matched against the path in the module, the import path
+		// would let a glob like api match every file of github.com/acme/api
+		if modPath, ok := modulePaths[profile.FileName]; ok && glob.Any(patterns, modPath) {
+			file.Excluded = file.Blocks
+		} else {
+			file.split()
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func (file *coverFile) split() {
+	if file.Path != "" {
+		if src, err := os.ReadFile(file.Path); err == nil {
+			file.src = src
+			file.fset = token.NewFileSet()
+			file.ast, _ = parser.ParseFile(file.fset, file.Path, src, parser.ParseComments)
+		}
+	}
+	if file.ast == nil {
+		file.Included = file.Blocks
+		return
+	} else if file.generated() {
+		file.Excluded = file.Blocks
+		return
+	}
+	regions := file.noCoverRegions()
+	for _, block := range file.Blocks {
+		if inRegions(block, regions) {
+			file.Excluded = append(file.Excluded, block)
+		} else {
+			file.Included = append(file.Included, block)
+		}
+	}
+}
+
+// generated checks for the standard generated code comment before the package
+// clause, see https://golang.org/s/generatedcode
+func (file *coverFile) generated() bool {
+	for _, group := range file.ast.Comments {
+		if group.Pos() >= file.ast.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if generatedPattern.MatchString(comment.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// noCoverRegions finds all of the statements and declarations marked with a
+// //og:nocover directive. The directive applies to the statement on the same
+// line, or the one directly after the comment.
+func (file *coverFile) noCoverRegions() []coverRegion {
+	regions := []coverRegion{}
+	for _, group := range file.ast.Comments {
+		for _, comment := range group.List {
+			if comment.Text != noCoverDirective && !strings.HasPrefix(comment.Text, noCoverDirective+" ") {
+				continue
+			}
+			lines := []int{file.fset.Position(comment.Pos()).Line, file.fset.Position(group.End()).Line + 1}
+			if node := file.nodeStartingOn(lines...); node != nil {
+				regions = append(regions, coverRegion{
+					start: file.fset.Position(node.Pos()),
+					end:   file.fset.Position(node.End()),
+				})
+			}
+		}
+	}
+	return regions
+}
+
+// nodeStartingOn finds the outermost statement or declaration that starts on
+// one of the lines
+func (file *coverFile) nodeStartingOn(lines ...int) ast.Node {
+	var found ast.Node
+	ast.Inspect(file.ast, func(node ast.Node) bool {
+		if found != nil {
+			return false
+		}
+		switch node.(type) {
+		case ast.Stmt, ast.Decl:
+			start := file.fset.Position(node.Pos()).Line
+			for _, line := range lines {
+				if start == line {
+					found = node
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
+
+func inRegions(block cover.ProfileBlock, regions []coverRegion) bool {
+	for _, region := range regions {
+		afterStart := block.StartLine > region.start.Line || (block.StartLine == region.start.Line && block.StartCol >= region.start.Column)
+		beforeEnd := block.EndLine < region.end.Line || (block.EndLine == region.end.Line && block.EndCol <= region.end.Column)
+		if afterStart && beforeEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// localFilePaths maps the import path file names in a profile to the go files
+// on disk, relative to the current directory, and to their path relative to
+// the root of their module.
+func localFilePaths(profiles []*cover.Profile) (map[string]string, map[string]string) {
+	pkgPaths := []string{}
+	seen := map[string]bool{}
+	for _, profile := range profiles {
+		if pkgPath := path.Dir(profile.FileName); !seen[pkgPath] {
+			seen[pkgPath] = true
+			pkgPaths = append(pkgPaths, pkgPath)
+		}
+	}
+	localPaths, modulePaths := map[string]string{}, map[string]string{}
+	if len(pkgPaths) == 0 {
+		return localPaths, modulePaths
+	}
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedFiles}, pkgPaths...)
+	if err != nil {
+		return localPaths, modulePaths
+	}
+	roots := map[string]string{}
+	for _, pkg := range pkgs {
+		for _, gofile := range pkg.GoFiles {
+			fileName := pkg.PkgPath + "/" + filepath.Base(gofile)
+			localPaths[fileName] = relPath(gofile)
+			dir := filepath.Dir(gofile)
+			if _, ok := roots[dir]; !ok {
+				roots[dir] = moduleRoot(dir)
+			}
+			if rel, err := filepath.Rel(roots[dir], gofile); roots[dir] != "" && err == nil {
+				modulePaths[fileName] = filepath.ToSlash(rel)
+			}
+		}
+	}
+	return localPaths, modulePaths
+}
+
+// moduleRoot finds the closest directory from dir up that has a go.mod
+func moduleRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func relPath(src string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return src
+	}
+	rel, err := filepath.Rel(wd, src)
+	if err != nil {
+		return src
+	} else if !strings.HasPrefix(rel, ".") {
+		rel = "./" + rel
+	}
+	return rel
+}
@@ -0,0 +1,124 @@
+package results
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const coverProfile = `mode: set
+github.com/tanema/og/nope/nope.go:3.28,5.2 1 1
+github.com/tanema/og/nope/nope.go:7.33,9.2 1 0
+github.com/tanema/og/nope/nope.go:3.28,5.2 1 0
+github.com/tanema/og/nope/nope.go:7.33,9.2 1 1
+github.com/tanema/og/untested/untested.go:3.28,5.2 2 0
+github.com/tanema/og/untested/untested.go:7.33,9.2 2 1
+`
+
+func TestParseCoverProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cover.out")
+	assert.Nil(t, os.WriteFile(path, []byte(coverProfile), 0644))
+
+	set := New("", 10*time.Minute)
+	set.Add(Pass, "github.com/tanema/og/nope", "", "")
+	set.parseCoverProfile(path, nil)
+
+	nope := set.Packages["github.com/tanema/og/nope"]
+	assert.Equal(t, int64(2), nope.StatementCount)
+	assert.Equal(t, int64(2), nope.CoveredCount)
+	assert.Equal(t, 100.0, nope.CoveragePercent)
+
+	untested := set.Packages["github.com/tanema/og/untested"]
+	assert.NotNil(t, untested)
+	assert.Equal(t, Skip, untested.State)
+	assert.Equal(t, int64(4), untested.StatementCount)
+	assert.Equal(t, int64(2), untested.CoveredCount)
+	assert.Equal(t, 50.0, untested.CoveragePercent)
+
+	assert.Equal(t, int64(6), set.StatementCount)
+	assert.Equal(t, int64(4), set.CoveredCount)
+	assert.Equal(t, 66.66, set.CoveragePercent)
+}
+
+func TestParseCoverProfileMissing(t *testing.T) {
+	set := New("", 10*time.Minute)
+	assert.Nil(t, set.parseCoverProfile(filepath.Join(t.TempDir(), "nope.out"), nil))
+	assert.Equal(t, int64(0), set.StatementCount)
+	assert.Empty(t, set.Packages)
+}
+
+func TestParseCoverProfileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cover.out")
+	assert.Nil(t, os.WriteFile(path, []byte("mode: set\nnot a block\n"), 0644))
+	assert.NotNil(t, New("", 10*time.Minute).parseCoverProfile(path, nil))
+
+	assert.Nil(t, os.WriteFile(path, []byte(excludeProfile), 0644))
+	assert.NotNil(t, New("", 10*time.Minute).parseCoverProfile(path, []string{"[z-a].go"}))
+}
+
+const excludeProfile = `mode: set
+github.com/tanema/og/_testdata/go.go:3.24,5.2 1 1
+github.com/tanema/og/_testdata/go.go:7.29,9.2 1 0
+github.com/tanema/og/_testdata/nocover.go:4.24,6.2 1 0
+github.com/tanema/og/_testdata/nocover.go:8.25,9.11 1 1
+github.com/tanema/og/_testdata/nocover.go:9.11,11.3 1 0
+github.com/tanema/og/_testdata/nocover.go:12.2,12.10 1 1
+github.com/tanema/og/_testdata/generated.go:5.22,7.2 1 0
+`
+
+func TestParseCoverProfileExclusions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cover.out")
+	assert.Nil(t, os.WriteFile(path, []byte(excludeProfile), 0644))
+
+	set := New("", 10*time.Minute)
+	set.parseCoverProfile(path, nil)
+	assert.Equal(t, int64(4), set.StatementCount)
+	assert.Equal(t, int64(3), set.CoveredCount)
+	assert.Equal(t, int64(3), set.ExcludedCount)
+	assert.Equal(t, int64(3), set.Packages["github.com/tanema/og/_testdata"].ExcludedCount)
+
+	set = New("", 10*time.Minute)
+	set.parseCoverProfile(path, []string{"go.go"})
+	assert.Equal(t, int64(2), set.StatementCount)
+	assert.Equal(t, int64(2), set.CoveredCount)
+	assert.Equal(t, int64(5), set.ExcludedCount)
+}
+
+func TestLoadCoverProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cover.out")
+	assert.Nil(t, os.WriteFile(path, []byte(excludeProfile), 0644))
+
+	files, err := loadCoverProfile(path, []string{"_testdata/go.go"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(files))
+	for _, file := range files {
+		switch filepath.Base(file.FileName) {
+		case "go.go":
+			assert.Empty(t, file.Included)
+			assert.Equal(t, 2, len(file.Excluded))
+		case "generated.go":
+			assert.Empty(t, file.Included)
+			assert.Equal(t, 1, len(file.Excluded))
+		case "nocover.go":
+			assert.Equal(t, 2, len(file.Included))
+			assert.Equal(t, 2, len(file.Excluded))
+			assert.Equal(t, 4, file.Excluded[0].StartLine)
+			assert.Equal(t, 9, file.Excluded[1].StartLine)
+		}
+	}
+
+	// segments of the module path do not exclude every file in the module
+	files, err = loadCoverProfile(path, []string{"og", "tanema", "github.com"})
+	assert.Nil(t, err)
+	for _, file := range files {
+		if filepath.Base(file.FileName) == "go.go" {
+			assert.Equal(t, 2, len(file.Included))
+		}
+	}
+
+	_, err = loadCoverProfile(path, []string{"[z-a].go"})
+	assert.NotNil(t, err)
+}
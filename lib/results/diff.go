@@ -0,0 +1,119 @@
+package results
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// regressionFloor is the least a test has to slow down by before it is
+// reported as a regression, so that very fast tests do not create noise.
+const regressionFloor = 100 * time.Millisecond
+
+type (
+	// Changes is the difference between two runs of the same tests
+	Changes struct {
+		NewFailures   []*Test           `json:"new_failures,omitempty"`
+		Fixed         []*Test           `json:"fixed,omitempty"`
+		Added         []*Test           `json:"added,omitempty"`
+		Removed       []*Test           `json:"removed,omitempty"`
+		Regressions   []*Regression     `json:"regressions,omitempty"`
+		Coverage      []*CoverageChange `json:"coverage,omitempty"`
+		CoverageDelta float64           `json:"coverage_delta,omitempty"`
+	}
+	// Regression is a test that got slower since the last run
+	Regression struct {
+		Test   *Test         `json:"test"`
+		Before time.Duration `json:"before"`
+		After  time.Duration `json:"after"`
+		Ratio  float64       `json:"ratio"`
+	}
+	// CoverageChange is a change in the coverage of a single package
+	CoverageChange struct {
+		Package string  `json:"package"`
+		Before  float64 `json:"before"`
+		After   float64 `json:"after"`
+		Delta   float64 `json:"delta"`
+	}
+)
+
+// Diff compares the set to a previous run of the same tests. Tests that got
+// slower by more than ratio are reported as regressions.
+func (set *Set) Diff(previous *Set, ratio float64) *Changes {
+	changes := &Changes{}
+	before, after := previous.tests(), set.tests()
+	for key, test := range after {
+		prev, ok := before[key]
+		if !ok {
+			changes.Added = append(changes.Added, test)
+			continue
+		}
+		if test.State == Fail && prev.State != Fail {
+			changes.NewFailures = append(changes.NewFailures, test)
+		} else if test.State == Pass && prev.State == Fail {
+			changes.Fixed = append(changes.Fixed, test)
+		}
+		if test.State == Pass && prev.State == Pass && ratio > 0 && prev.Elapsed() > 0 {
+			growth := float64(test.Elapsed()) / float64(prev.Elapsed())
+			if growth > ratio && test.Elapsed()-prev.Elapsed() >= regressionFloor {
+				changes.Regressions = append(changes.Regressions, &Regression{Test: test, Before: prev.Elapsed(), After: test.Elapsed(), Ratio: growth})
+			}
+		}
+	}
+	for key, test := range before {
+		if _, ok := after[key]; !ok {
+			changes.Removed = append(changes.Removed, test)
+		}
+	}
+	for name, pkg := range set.Packages {
+		if prev, ok := previous.Packages[name]; ok && (pkg.StatementCount > 0 || prev.StatementCount > 0) {
+			if delta := roundPercent(pkg.CoveragePercent - prev.CoveragePercent); delta != 0 {
+				changes.Coverage = append(changes.Coverage, &CoverageChange{Package: name, Before: prev.CoveragePercent, After: pkg.CoveragePercent, Delta: delta})
+			}
+		}
+	}
+	if set.StatementCount > 0 && previous.StatementCount > 0 {
+		changes.CoverageDelta = roundPercent(set.CoveragePercent - previous.CoveragePercent)
+	}
+	for _, tests := range [][]*Test{changes.NewFailures, changes.Fixed, changes.Added, changes.Removed} {
+		sortTests(tests)
+	}
+	sort.Slice(changes.Regressions, func(i, j int) bool {
+		return changes.Regressions[i].Ratio > changes.Regressions[j].Ratio
+	})
+	sort.Slice(changes.Coverage, func(i, j int) bool {
+		return changes.Coverage[i].Package < changes.Coverage[j].Package
+	})
+	return changes
+}
+
+// Empty is true if nothing changed between the runs
+func (changes *Changes) Empty() bool {
+	return len(changes.NewFailures) == 0 && len(changes.Fixed) == 0 &&
+		len(changes.Added) == 0 && len(changes.Removed) == 0 &&
+		len(changes.Regressions) == 0 && len(changes.Coverage) == 0 &&
+		changes.CoverageDelta == 0
+}
+
+func (set *Set) tests() map[string]*Test {
+	tests := map[string]*Test{}
+	for _, pkg := range set.Packages {
+		for _, test := range pkg.Tests {
+			tests[test.Package+"#"+test.Name] = test
+		}
+	}
+	return tests
+}
+
+func sortTests(tests []*Test) {
+	sort.Slice(tests, func(i, j int) bool {
+		if tests[i].Package != tests[j].Package {
+			return tests[i].Package < tests[j].Package
+		}
+		return tests[i].Name < tests[j].Name
+	})
+}
+
+func roundPercent(percent float64) float64 {
+	return math.Round(percent*100) / 100
+}
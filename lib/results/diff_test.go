@@ -0,0 +1,48 @@
+package results
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDiff(t *testing.T) {
+	setup := func(states map[string]Action) *Set {
+		set := New("", 10*time.Minute)
+		for name, state := range states {
+			set.Add(Run, "nope", name, "")
+			set.Add(state, "nope", name, "")
+		}
+		return set
+	}
+	previous := setup(map[string]Action{"TestFixed": Fail, "TestBroken": Pass, "TestSlow": Pass, "TestRemoved": Pass, "TestSame": Pass})
+	current := setup(map[string]Action{"TestFixed": Pass, "TestBroken": Fail, "TestSlow": Pass, "TestAdded": Pass, "TestSame": Pass})
+	previous.Packages["nope"].Tests["TestSlow"].Total = 100 * time.Millisecond
+	current.Packages["nope"].Tests["TestSlow"].Total = 500 * time.Millisecond
+	previous.Packages["nope"].Tests["TestSame"].Total = time.Millisecond
+	current.Packages["nope"].Tests["TestSame"].Total = 5 * time.Millisecond
+	previous.Packages["nope"].StatementCount, previous.Packages["nope"].CoveragePercent = 10, 50
+	current.Packages["nope"].StatementCount, current.Packages["nope"].CoveragePercent = 10, 60
+	previous.StatementCount, previous.CoveragePercent = 10, 50
+	current.StatementCount, current.CoveragePercent = 10, 60
+
+	changes := current.Diff(previous, 1.5)
+	assert.False(t, changes.Empty())
+	assert.Equal(t, 1, len(changes.NewFailures))
+	assert.Equal(t, "TestBroken", changes.NewFailures[0].Name)
+	assert.Equal(t, 1, len(changes.Fixed))
+	assert.Equal(t, "TestFixed", changes.Fixed[0].Name)
+	assert.Equal(t, 1, len(changes.Added))
+	assert.Equal(t, "TestAdded", changes.Added[0].Name)
+	assert.Equal(t, 1, len(changes.Removed))
+	assert.Equal(t, "TestRemoved", changes.Removed[0].Name)
+	assert.Equal(t, 1, len(changes.Regressions))
+	assert.Equal(t, "TestSlow", changes.Regressions[0].Test.Name)
+	assert.Equal(t, 5.0, changes.Regressions[0].Ratio)
+	assert.Equal(t, []*CoverageChange{{Package: "nope", Before: 50, After: 60, Delta: 10}}, changes.Coverage)
+	assert.Equal(t, 10.0, changes.CoverageDelta)
+
+	assert.True(t, current.Diff(current, 1.5).Empty())
+	assert.Empty(t, current.Diff(previous, 0).Regressions)
+}
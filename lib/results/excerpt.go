@@ -47,11 +47,16 @@ func (line *BuildError) Excerpt() *Excerpt {
 	}
 	if scanner.Scan() {
 		text := scanner.Text()
+		col := clamp(int(line.Column), 1, len(text))
+		start, end := clamp(col-1, 0, len(text)), col
+		if len(text) == 0 {
+			end = 0
+		}
 		excpt.Highlight = &ExcerptHighlightLine{
 			Line:      leftPad(line.Line, digitCount),
-			Prefix:    strings.ReplaceAll(text[:max(0, line.Column-1)], "\t", "  "),
-			Highlight: strings.ReplaceAll(text[max(0, line.Column-1):line.Column], "\t", "  "),
-			Suffix:    strings.ReplaceAll(text[line.Column:], "\t", "  "),
+			Prefix:    strings.ReplaceAll(text[:start], "\t", "  "),
+			Highlight: strings.ReplaceAll(text[start:end], "\t", "  "),
+			Suffix:    strings.ReplaceAll(text[end:], "\t", "  "),
 		}
 	} else {
 		return nil
@@ -55,6 +55,15 @@ func TestExcerpt(t *testing.T) {
 	assert.Equal(t, *expected.Before, *actual.Before)
 	assert.Equal(t, *expected.Highlight, *actual.Highlight)
 	assert.Nil(t, actual.After)
+
+	err = BuildError{
+		Path:   "../../_testdata/go.go",
+		Line:   17,
+		Column: 40,
+	}
+	actual = err.Excerpt()
+	assert.NotNil(t, actual)
+	assert.Equal(t, ExcerptHighlightLine{Line: "17", Prefix: "", Highlight: "}", Suffix: ""}, *actual.Highlight)
 }
 
 func TestDigits(t *testing.T) {
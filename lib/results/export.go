@@ -0,0 +1,169 @@
+package results
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+type (
+	// coberturaCoverage is the root of a cobertura xml report
+	coberturaCoverage struct {
+		XMLName         xml.Name            `xml:"coverage"`
+		LineRate        float64             `xml:"line-rate,attr"`
+		BranchRate      float64             `xml:"branch-rate,attr"`
+		LinesCovered    int64               `xml:"lines-covered,attr"`
+		LinesValid      int64               `xml:"lines-valid,attr"`
+		BranchesCovered int64               `xml:"branches-covered,attr"`
+		BranchesValid   int64               `xml:"branches-valid,attr"`
+		Complexity      float64             `xml:"complexity,attr"`
+		Version         string              `xml:"version,attr"`
+		Timestamp       int64               `xml:"timestamp,attr"`
+		Sources         []string            `xml:"sources>source"`
+		Packages        []*coberturaPackage `xml:"packages>package"`
+	}
+	coberturaPackage struct {
+		Name       string            `xml:"name,attr"`
+		LineRate   float64           `xml:"line-rate,attr"`
+		BranchRate float64           `xml:"branch-rate,attr"`
+		Complexity float64           `xml:"complexity,attr"`
+		Classes    []*coberturaClass `xml:"classes>class"`
+		covered    int64
+		valid      int64
+	}
+	coberturaClass struct {
+		Name       string           `xml:"name,attr"`
+		Filename   string           `xml:"filename,attr"`
+		LineRate   float64          `xml:"line-rate,attr"`
+		BranchRate float64          `xml:"branch-rate,attr"`
+		Complexity float64          `xml:"complexity,attr"`
+		Methods    struct{}         `xml:"methods"`
+		Lines      []*coberturaLine `xml:"lines>line"`
+	}
+	coberturaLine struct {
+		Number int `xml:"number,attr"`
+		Hits   int `xml:"hits,attr"`
+	}
+)
+
+// WriteLCOV converts a cover profile into an lcov tracefile. In count or
+// atomic mode the real hit counts are reported, in set mode lines are only
+// ever hit once.
+func WriteLCOV(w io.Writer, coverPath string, excludes []string) error {
+	files, err := loadCoverProfile(coverPath, excludes)
+	if err != nil {
+		return fmt.Errorf("cannot read cover profile: %v", err)
+	}
+	for _, file := range files {
+		lines, hits := file.lineHits()
+		if len(lines) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "TN:\nSF:%v\n", file.sourcePath()); err != nil {
+			return err
+		}
+		linesHit := 0
+		for _, line := range lines {
+			if hits[line] > 0 {
+				linesHit++
+			}
+			if _, err := fmt.Fprintf(w, "DA:%v,%v\n", line, hits[line]); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "LF:%v\nLH:%v\nend_of_record\n", len(lines), linesHit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCobertura converts a cover profile into a cobertura xml report, with a
+// package for each go package and a class for each file.
+func WriteCobertura(w io.Writer, coverPath string, excludes []string) error {
+	files, err := loadCoverProfile(coverPath, excludes)
+	if err != nil {
+		return fmt.Errorf("cannot read cover profile: %v", err)
+	}
+	wd, _ := os.Getwd()
+	report := &coberturaCoverage{Timestamp: time.Now().UnixNano() / int64(time.Millisecond), Sources: []string{wd}}
+	pkgs := map[string]*coberturaPackage{}
+	for _, file := range files {
+		lines, hits := file.lineHits()
+		if len(lines) == 0 {
+			continue
+		}
+		pkgName := path.Dir(file.FileName)
+		if _, ok := pkgs[pkgName]; !ok {
+			pkgs[pkgName] = &coberturaPackage{Name: pkgName}
+			report.Packages = append(report.Packages, pkgs[pkgName])
+		}
+		pkg := pkgs[pkgName]
+		class := &coberturaClass{Name: path.Base(file.FileName), Filename: file.sourcePath()}
+		covered := int64(0)
+		for _, line := range lines {
+			if hits[line] > 0 {
+				covered++
+			}
+			class.Lines = append(class.Lines, &coberturaLine{Number: line, Hits: hits[line]})
+		}
+		class.LineRate = lineRate(covered, int64(len(lines)))
+		pkg.Classes = append(pkg.Classes, class)
+		pkg.covered += covered
+		pkg.valid += int64(len(lines))
+		report.LinesCovered += covered
+		report.LinesValid += int64(len(lines))
+	}
+	for _, pkg := range report.Packages {
+		pkg.LineRate = lineRate(pkg.covered, pkg.valid)
+	}
+	report.LineRate = lineRate(report.LinesCovered, report.LinesValid)
+	if _, err := io.WriteString(w, xml.Header+`<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`+"\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// lineHits maps the included blocks onto the lines they span. Lines shared by
+// several blocks report the highest count.
+func (file *coverFile) lineHits() ([]int, map[int]int) {
+	hits := map[int]int{}
+	for _, block := range file.Included {
+		for line := block.StartLine; line <= block.EndLine; line++ {
+			if count, ok := hits[line]; !ok || block.Count > count {
+				hits[line] = block.Count
+			}
+		}
+	}
+	lines := make([]int, 0, len(hits))
+	for line := range hits {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines, hits
+}
+
+func (file *coverFile) sourcePath() string {
+	if file.Path == "" {
+		return file.FileName
+	}
+	return filepath.ToSlash(filepath.Clean(file.Path))
+}
+
+func lineRate(covered, valid int64) float64 {
+	if valid == 0 {
+		return 0
+	}
+	return float64(covered) / float64(valid)
+}
@@ -0,0 +1,55 @@
+package results
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const countProfile = `mode: count
+github.com/tanema/og/_testdata/go.go:3.24,5.2 1 3
+github.com/tanema/og/_testdata/go.go:7.29,9.2 1 0
+github.com/tanema/og/_testdata/generated.go:5.22,7.2 1 0
+`
+
+func TestWriteLCOV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cover.out")
+	assert.Nil(t, os.WriteFile(path, []byte(countProfile), 0644))
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteLCOV(&buf, path, nil))
+	assert.Equal(t, `TN:
+SF:../../_testdata/go.go
+DA:3,3
+DA:4,3
+DA:5,3
+DA:7,0
+DA:8,0
+DA:9,0
+LF:6
+LH:3
+end_of_record
+`, buf.String())
+
+	assert.NotNil(t, WriteLCOV(&buf, filepath.Join(t.TempDir(), "nope.out"), nil))
+}
+
+func TestWriteCobertura(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cover.out")
+	assert.Nil(t, os.WriteFile(path, []byte(countProfile), 0644))
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteCobertura(&buf, path, nil))
+	out := buf.String()
+	assert.Contains(t, out, `<coverage line-rate="0.5" branch-rate="0" lines-covered="3" lines-valid="6"`)
+	assert.Contains(t, out, `<package name="github.com/tanema/og/_testdata" line-rate="0.5"`)
+	assert.Contains(t, out, `<class name="go.go" filename="../../_testdata/go.go" line-rate="0.5"`)
+	assert.Contains(t, out, `<line number="4" hits="3"></line>`)
+	assert.Contains(t, out, `<line number="8" hits="0"></line>`)
+	assert.NotContains(t, out, "generated.go")
+
+	assert.NotNil(t, WriteCobertura(&buf, filepath.Join(t.TempDir(), "nope.out"), nil))
+}
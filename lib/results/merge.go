@@ -0,0 +1,212 @@
+package results
+
+import "sort"
+
+// Conflict is a test that ran in more than one of the merged sets
+type Conflict struct {
+	Package string   `json:"package"`
+	Name    string   `json:"name"`
+	States  []Action `json:"states"`
+}
+
+// Merge combines the results of sets that each ran a part of the tests, like
+// sharded CI jobs, into a single set. If the same test ran in more than one set
+// it is recorded as a conflict and a failure is kept over any other result.
+// Shards run in parallel so the elapsed time of the merged set is the longest
+// shard, while package times are the sum of the time spent in each shard.
+func Merge(sets ...*Set) *Set {
+	merged := &Set{
+		stopwatch:   &stopwatch{},
+		State:       Pass,
+		Packages:    map[string]*Package{},
+		BuildErrors: []*BuildError{},
+	}
+	slow := map[string]bool{}
+	for i, set := range sets {
+		if i == 0 {
+			merged.Name, merged.threshold = set.Name, set.threshold
+		}
+		if set.State == Fail {
+			merged.State = Fail
+		}
+		if elapsed := set.Elapsed(); elapsed > merged.Total {
+			merged.Total = elapsed
+		}
+		for _, builderr := range set.BuildErrors {
+			if !merged.hasBuildError(builderr.Raw) {
+				merged.BuildErrors = append(merged.BuildErrors, builderr)
+			}
+		}
+		for _, test := range set.SlowTests {
+			slow[test.Package+"#"+test.Name] = true
+		}
+		for name, pkg := range set.Packages {
+			merged.mergePackage(name, pkg)
+		}
+	}
+	merged.summarize(slow)
+	return merged
+}
+
+func (set *Set) mergePackage(name string, pkg *Package) {
+	current, ok := set.Packages[name]
+	if !ok {
+		current = &Package{
+			stopwatch: &stopwatch{},
+			Name:      pkg.Name,
+			State:     pkg.State,
+			Cached:    pkg.Cached,
+			Tests:     map[string]*Test{},
+		}
+		set.Packages[name] = current
+	} else {
+		current.State = mergeState(current.State, pkg.State)
+		current.Cached = current.Cached && pkg.Cached
+	}
+	current.Total += pkg.Elapsed()
+	// with -coverpkg every shard reports on the same statements, so the counts
+	// are taken again from the union of the blocks when summarizing. Results
+	// saved without blocks can only use the best covered shard.
+	current.CoverBlocks = mergeBlocks(current.CoverBlocks, pkg.CoverBlocks)
+	current.StatementCount = max(current.StatementCount, pkg.StatementCount)
+	current.CoveredCount = max(current.CoveredCount, pkg.CoveredCount)
+	current.ExcludedCount = max(current.ExcludedCount, pkg.ExcludedCount)
+	for testName, test := range pkg.Tests {
+		existing, ok := current.Tests[testName]
+		if !ok {
+			current.Tests[testName] = test
+			continue
+		}
+		set.addConflict(existing, test)
+		if test.State == Fail && existing.State != Fail {
+			current.Tests[testName] = test
+		}
+	}
+}
+
+func (set *Set) addConflict(existing, test *Test) {
+	for _, conflict := range set.Conflicts {
+		if conflict.Package == test.Package && conflict.Name == test.Name {
+			conflict.States = append(conflict.States, test.State)
+			return
+		}
+	}
+	set.Conflicts = append(set.Conflicts, &Conflict{
+		Package: test.Package,
+		Name:    test.Name,
+		States:  []Action{existing.State, test.State},
+	})
+}
+
+// summarize counts up the merged packages and tests again since the same
+// package can be in more than one set.
+func (set *Set) summarize(slow map[string]bool) {
+	for _, pkg := range set.Packages {
+		pkg.Summary = Summary{}
+		// packages that are only in the results for coverage never ran
+		if pkg.State != Skip || pkg.Total > 0 {
+			switch pkg.State {
+			case Pass:
+				set.PkgSummary.Pass++
+			case Fail:
+				set.PkgSummary.Fail++
+			case Skip:
+				set.PkgSummary.Skip++
+			}
+		}
+		if pkg.Cached {
+			set.Cached++
+		}
+		for _, test := range pkg.Tests {
+			set.TotalTests++
+			switch test.State {
+			case Pass:
+				pkg.Pass++
+				set.TestSummary.Pass++
+			case Fail:
+				pkg.Fail++
+				set.TestSummary.Fail++
+				set.FailedTests = append(set.FailedTests, test)
+			case Skip:
+				pkg.Skip++
+				set.TestSummary.Skip++
+				set.SkippedTests = append(set.SkippedTests, test)
+			}
+			if slow[test.Package+"#"+test.Name] {
+				set.SlowTests = append(set.SlowTests, test)
+			}
+		}
+		if len(pkg.CoverBlocks) > 0 {
+			pkg.countBlocks()
+		}
+		pkg.CoveragePercent = calcPercent(pkg.StatementCount, pkg.CoveredCount)
+		set.StatementCount += pkg.StatementCount
+		set.CoveredCount += pkg.CoveredCount
+		set.ExcludedCount += pkg.ExcludedCount
+	}
+	set.CoveragePercent = calcPercent(set.StatementCount, set.CoveredCount)
+	sortTests(set.FailedTests)
+	sortTests(set.SkippedTests)
+	sort.SliceStable(set.SlowTests, func(i, j int) bool {
+		return set.SlowTests[i].Elapsed() > set.SlowTests[j].Elapsed()
+	})
+	sort.Slice(set.Conflicts, func(i, j int) bool {
+		if set.Conflicts[i].Package != set.Conflicts[j].Package {
+			return set.Conflicts[i].Package < set.Conflicts[j].Package
+		}
+		return set.Conflicts[i].Name < set.Conflicts[j].Name
+	})
+}
+
+// blockPosition identifies a cover block across sets
+type blockPosition struct {
+	file                                 string
+	startLine, startCol, endLine, endCol int
+}
+
+func (block *CoverBlock) position() blockPosition {
+	return blockPosition{block.File, block.StartLine, block.StartCol, block.EndLine, block.EndCol}
+}
+
+// mergeBlocks adds the blocks to the blocks of a package, adding the counts of
+// blocks that are in both.
+func mergeBlocks(blocks, others []*CoverBlock) []*CoverBlock {
+	index := map[blockPosition]*CoverBlock{}
+	for _, block := range blocks {
+		index[block.position()] = block
+	}
+	for _, block := range others {
+		if existing, ok := index[block.position()]; ok {
+			existing.Count += block.Count
+			continue
+		}
+		merged := *block
+		blocks = append(blocks, &merged)
+		index[merged.position()] = &merged
+	}
+	return blocks
+}
+
+// countBlocks counts the statements of a package from its blocks
+func (pkg *Package) countBlocks() {
+	pkg.StatementCount, pkg.CoveredCount, pkg.ExcludedCount = 0, 0, 0
+	for _, block := range pkg.CoverBlocks {
+		if block.Excluded {
+			pkg.ExcludedCount += int64(block.NumStmt)
+		} else {
+			pkg.StatementCount += int64(block.NumStmt)
+			if block.Count > 0 {
+				pkg.CoveredCount += int64(block.NumStmt)
+			}
+		}
+	}
+}
+
+func mergeState(a, b Action) Action {
+	if a == Fail || b == Fail {
+		return Fail
+	} else if a == Pass || b == Pass {
+		return Pass
+	}
+	return a
+}
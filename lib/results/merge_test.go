@@ -0,0 +1,84 @@
+package results
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	setup := func(pkgState Action, states map[string]Action) *Set {
+		set := New("", 0)
+		for name, state := range states {
+			set.Add(Run, "nope", name, "")
+			set.Add(state, "nope", name, "")
+		}
+		set.Add(pkgState, "nope", "", "")
+		set.Complete(false, "")
+		return set
+	}
+	first := setup(Pass, map[string]Action{"TestA": Pass, "TestB": Skip, "TestShared": Pass})
+	second := setup(Fail, map[string]Action{"TestC": Fail, "TestShared": Fail})
+	second.Add(Pass, "other", "", "")
+	first.Packages["nope"].StatementCount, first.Packages["nope"].CoveredCount = 10, 4
+	second.Packages["nope"].StatementCount, second.Packages["nope"].CoveredCount = 10, 6
+	first.Total, second.Total = time.Second, 2*time.Second
+	first.SlowTests = []*Test{first.Packages["nope"].Tests["TestA"]}
+	second.SlowTests = nil
+
+	merged := Merge(first, second)
+	assert.Equal(t, Fail, merged.State)
+	assert.Equal(t, 2*time.Second, merged.Elapsed())
+	assert.Equal(t, 4, merged.TotalTests)
+	assert.Equal(t, Summary{Pass: 1, Fail: 2, Skip: 1}, merged.TestSummary)
+	assert.Equal(t, Summary{Pass: 1, Fail: 1}, merged.PkgSummary)
+	assert.Equal(t, Fail, merged.Packages["nope"].State)
+	assert.Equal(t, Summary{Pass: 1, Fail: 2, Skip: 1}, merged.Packages["nope"].Summary)
+	assert.Equal(t, 2, len(merged.FailedTests))
+	assert.Equal(t, "TestC", merged.FailedTests[0].Name)
+	assert.Equal(t, "TestShared", merged.FailedTests[1].Name)
+	assert.Equal(t, 1, len(merged.SkippedTests))
+	assert.Equal(t, 1, len(merged.SlowTests))
+	assert.Equal(t, int64(10), merged.StatementCount)
+	assert.Equal(t, int64(6), merged.CoveredCount)
+	assert.Equal(t, 60.0, merged.CoveragePercent)
+	assert.Equal(t, []*Conflict{{Package: "nope", Name: "TestShared", States: []Action{Pass, Fail}}}, merged.Conflicts)
+}
+
+func TestMergeState(t *testing.T) {
+	assert.Equal(t, Fail, mergeState(Pass, Fail))
+	assert.Equal(t, Pass, mergeState(Skip, Pass))
+	assert.Equal(t, Skip, mergeState(Skip, Skip))
+}
+
+func TestMergeCoverBlocks(t *testing.T) {
+	setup := func(counts ...int) *Set {
+		set := New("", 0)
+		set.Add(Pass, "nope", "", "")
+		pkg := set.Packages["nope"]
+		for i, count := range counts {
+			pkg.CoverBlocks = append(pkg.CoverBlocks, &CoverBlock{File: "nope/a.go", StartLine: i + 1, EndLine: i + 1, NumStmt: 2, Count: count})
+		}
+		pkg.CoverBlocks = append(pkg.CoverBlocks, &CoverBlock{File: "nope/gen.go", StartLine: 1, EndLine: 1, NumStmt: 5, Excluded: true})
+		pkg.countBlocks()
+		set.Complete(false, "")
+		return set
+	}
+	// each shard covers a different half of the blocks
+	first := setup(1, 1, 0, 0)
+	second := setup(0, 0, 3, 0)
+	assert.Equal(t, int64(4), first.Packages["nope"].CoveredCount)
+
+	merged := Merge(first, second)
+	pkg := merged.Packages["nope"]
+	assert.Equal(t, 5, len(pkg.CoverBlocks))
+	assert.Equal(t, int64(8), pkg.StatementCount)
+	assert.Equal(t, int64(6), pkg.CoveredCount)
+	assert.Equal(t, int64(5), pkg.ExcludedCount)
+	assert.Equal(t, int64(6), merged.CoveredCount)
+	assert.Equal(t, 75.0, merged.CoveragePercent)
+	// the blocks of the merged sets are not changed
+	assert.Equal(t, 0, second.Packages["nope"].CoverBlocks[0].Count)
+	assert.Equal(t, 1, first.Packages["nope"].CoverBlocks[0].Count)
+}
@@ -1,33 +1,61 @@
 package results
 
 import (
+	"encoding/json"
 	"strings"
+	"time"
 )
 
-// Package is the test results for a single package
-type Package struct {
-	*stopwatch
-	Summary
-	Name            string           `json:"name"`
-	Tests           map[string]*Test `json:"tests,omitempty"`
-	State           Action           `json:"state"`
-	Cached          bool             `json:"cached,omitempty"`
-	StatementCount  int64            `json:"statements,omitempty"`
-	CoveredCount    int64            `json:"covered,omitempty"`
-	CoveragePercent float64          `json:"percent,omitempty"`
-}
+type (
+	// Package is the test results for a single package
+	Package struct {
+		*stopwatch
+		Summary
+		Name            string           `json:"name"`
+		Tests           map[string]*Test `json:"tests,omitempty"`
+		State           Action           `json:"state"`
+		Cached          bool             `json:"cached,omitempty"`
+		StatementCount  int64            `json:"statements,omitempty"`
+		CoveredCount    int64            `json:"covered,omitempty"`
+		ExcludedCount   int64            `json:"excluded,omitempty"`
+		CoveragePercent float64          `json:"percent,omitempty"`
+		CoverBlocks     []*CoverBlock    `json:"cover_blocks,omitempty"`
+	}
+	// CoverBlock is a block of statements from the cover profile. They are kept
+	// so that merged sets can count the blocks covered by any of them.
+	CoverBlock struct {
+		File      string `json:"file"`
+		StartLine int    `json:"start_line"`
+		StartCol  int    `json:"start_col"`
+		EndLine   int    `json:"end_line"`
+		EndCol    int    `json:"end_col"`
+		NumStmt   int    `json:"statements"`
+		Count     int    `json:"count"`
+		Excluded  bool   `json:"excluded,omitempty"`
+	}
+)
 
-func newPackage(name string) *Package {
+func newPackage(name string, at time.Time) *Package {
 	pkg := &Package{
 		stopwatch: &stopwatch{},
 		Name:      name,
 		State:     Run,
 		Tests:     map[string]*Test{},
 	}
-	pkg.start()
+	pkg.start(at)
 	return pkg
 }
 
+// UnmarshalJSON decodes a package that was previously encoded with json.Marshal
+func (pkg *Package) UnmarshalJSON(data []byte) error {
+	type plainPackage Package
+	plain := (*plainPackage)(pkg)
+	if plain.stopwatch == nil {
+		plain.stopwatch = &stopwatch{}
+	}
+	return json.Unmarshal(data, plain)
+}
+
 func (pkg *Package) result(set *Set, action Action, output string) {
 	switch action {
 	case Pass:
@@ -38,9 +66,9 @@ func (pkg *Package) result(set *Set, action Action, output string) {
 	case Skip:
 		set.PkgSummary.Skip++
 	case Run, Continue:
-		pkg.start()
+		pkg.start(set.now())
 	case Pause:
-		pkg.pause()
+		pkg.pause(set.now())
 	case Output:
 		if strings.HasPrefix(output, "ok") && strings.Contains(output, "(cached)") {
 			set.Cached++
@@ -51,6 +79,6 @@ func (pkg *Package) result(set *Set, action Action, output string) {
 		pkg.State = action
 	}
 	if action == Pass || action == Fail || action == Skip {
-		pkg.stop()
+		pkg.finish(set.now(), set.eventElapsed())
 	}
 }
@@ -1,12 +1,11 @@
 package results
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"math"
-	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -14,7 +13,9 @@ import (
 	"golang.org/x/tools/go/packages"
 )
 
-var coverLinePat = regexp.MustCompile(`^(.+):([0-9]+)\.([0-9]+),([0-9]+)\.([0-9]+) ([0-9]+) ([0-9]+)$`)
+// SchemaVersion is the version of the json encoding of a Set. It is increased
+// whenever a change would stop older versions of og from reading it.
+const SchemaVersion = 1
 
 // Action is the states of the tests
 type Action string
@@ -27,6 +28,10 @@ const (
 	Continue Action = "cont"
 	Pause    Action = "pause"
 	Output   Action = "output"
+	// BuildOutput and BuildFail are reported by go test when a test binary
+	// fails to build, instead of writing the errors to stderr
+	BuildOutput Action = "build-output"
+	BuildFail   Action = "build-fail"
 )
 
 type (
@@ -39,7 +44,7 @@ type (
 	// Set is the complete test results for all packages
 	Set struct {
 		*stopwatch
-		Name            string
+		Name            string              `json:"name"`
 		TestSummary     Summary             `json:"test_summary"`
 		PkgSummary      Summary             `json:"pkg_summary"`
 		Cached          int                 `json:"cached"`
@@ -49,12 +54,29 @@ type (
 		BuildErrors     []*BuildError       `json:"build_errors,omitempty"`
 		StatementCount  int64               `json:"statements,omitempty"`
 		CoveredCount    int64               `json:"covered,omitempty"`
+		ExcludedCount   int64               `json:"excluded,omitempty"`
 		CoveragePercent float64             `json:"percent,omitempty"`
 		FailedTests     []*Test             `json:"failed_tests,omitempty"`
 		SkippedTests    []*Test             `json:"skipped_tests,omitempty"`
 		SlowTests       []*Test             `json:"slow_tests,omitempty"`
+		Conflicts       []*Conflict         `json:"conflicts,omitempty"`
+		Shard           *Shard              `json:"shard,omitempty"`
+		Filter          string              `json:"filter,omitempty"`
 		threshold       time.Duration
 		path            string
+		replay          bool
+		withTimeline    bool
+		event           *logLine
+		firstEvent      time.Time
+		lastEvent       time.Time
+	}
+	// Shard records the part of the tests that a sharded run ran. Packages are
+	// run whole, while only the listed tests ran for packages in Tests.
+	Shard struct {
+		Index    int                 `json:"index"`
+		Total    int                 `json:"total"`
+		Packages []string            `json:"packages,omitempty"`
+		Tests    map[string][]string `json:"tests,omitempty"`
 	}
 	// BuildError captures a single build error in a package
 	BuildError struct {
@@ -68,10 +90,12 @@ type (
 		Raw     string `json:"raw"`
 	}
 	logLine struct {
+		Time    time.Time
 		Package string
 		Test    string
 		Action  Action
 		Output  string
+		Elapsed *float64
 	}
 )
 
@@ -91,29 +115,153 @@ func New(path string, threshold time.Duration) *Set {
 		BuildErrors: []*BuildError{},
 		threshold:   threshold,
 	}
-	set.start()
+	set.start(time.Now())
 	return set
 }
 
+// plainSet has the fields of a Set without its json methods
+type plainSet Set
+
+// setJSON is the encoded form of a Set, it adds the schema version and the
+// state that is not exported so that a set can be rendered again.
+type setJSON struct {
+	Version   int           `json:"version"`
+	Threshold time.Duration `json:"threshold,omitempty"`
+	Timeline  *Timeline     `json:"timeline,omitempty"`
+	*plainSet
+}
+
+// MarshalJSON encodes the set along with the schema version
+func (set *Set) MarshalJSON() ([]byte, error) {
+	encoded := setJSON{Version: SchemaVersion, Threshold: set.threshold, plainSet: (*plainSet)(set)}
+	if set.withTimeline {
+		encoded.Timeline = set.Timeline()
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes a set that was previously encoded with json.Marshal,
+// like the output of --dump. Sets encoded before the schema was versioned are
+// read as version 1.
+func (set *Set) UnmarshalJSON(data []byte) error {
+	plain := setJSON{plainSet: (*plainSet)(set)}
+	if plain.stopwatch == nil {
+		plain.stopwatch = &stopwatch{}
+	}
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return err
+	} else if plain.Version > SchemaVersion {
+		return fmt.Errorf("results were saved with schema version %v but only %v is supported", plain.Version, SchemaVersion)
+	}
+	set.threshold = plain.Threshold
+	return nil
+}
+
 // Parse will parse a reader line by line, adding the lines to the setult set.
 // decor is a callback that can be used for displaying results
 func (set *Set) Parse(data []byte) {
 	line := &logLine{}
-	if err := json.Unmarshal(data, &line); err == nil {
-		set.Add(line.Action, line.Package, line.Test, line.Output)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return
+	} else if err := json.Unmarshal(data, &line); err != nil {
+		// anything that is not an event is build output, like when stderr was
+		// piped along with the events
+		set.ParseError(data)
+		return
+	}
+	switch line.Action {
+	case BuildOutput:
+		// the package header is only noise since the package is looked up from
+		// the error path, and with -coverpkg the same error is reported for
+		// each build of the package
+		output := strings.TrimSuffix(line.Output, "\n")
+		if !strings.HasPrefix(output, "# ") && !set.hasBuildError(output) {
+			set.ParseError([]byte(output))
+		}
+	case BuildFail:
+		set.State = Fail
+	default:
+		set.addEvent(line)
 	}
 }
 
-// Complete will mark the set as finished
-func (set *Set) Complete(shouldCover bool, coverProfile string) {
-	defer set.stop()
+// IncludeTimeline adds the timeline to the json encoding of the set. It is
+// only read back as the intervals that it was made from.
+func (set *Set) IncludeTimeline() {
+	set.withTimeline = true
+}
+
+// Compact copies the set without its cover blocks and intervals, which are
+// only needed for merging and timelines, so that many runs can be kept cheaply.
+func (set *Set) Compact() *Set {
+	compact := *set
+	compact.stopwatch = set.stopwatch.compact()
+	compact.withTimeline = false
+	compact.Packages = map[string]*Package{}
+	copies := map[*Test]*Test{}
+	for name, pkg := range set.Packages {
+		compactPkg := *pkg
+		compactPkg.stopwatch = pkg.stopwatch.compact()
+		compactPkg.CoverBlocks = nil
+		compactPkg.Tests = map[string]*Test{}
+		for testName, test := range pkg.Tests {
+			compactTest := *test
+			compactTest.stopwatch = test.stopwatch.compact()
+			compactPkg.Tests[testName] = &compactTest
+			copies[test] = &compactTest
+		}
+		compact.Packages[name] = &compactPkg
+	}
+	compact.FailedTests = compactTests(set.FailedTests, copies)
+	compact.SkippedTests = compactTests(set.SkippedTests, copies)
+	compact.SlowTests = compactTests(set.SlowTests, copies)
+	return &compact
+}
+
+func compactTests(tests []*Test, copies map[*Test]*Test) []*Test {
+	if tests == nil {
+		return nil
+	}
+	compact := make([]*Test, 0, len(tests))
+	for _, test := range tests {
+		if copied, ok := copies[test]; ok {
+			compact = append(compact, copied)
+		} else {
+			copied := *test
+			copied.stopwatch = test.stopwatch.compact()
+			compact = append(compact, &copied)
+		}
+	}
+	return compact
+}
+
+// Replay marks the set as reading events that were recorded earlier, like from
+// a file. The elapsed time of the set is then taken from the time of the events
+// instead of how long it took to read them.
+func (set *Set) Replay() {
+	set.replay = true
+}
+
+// Complete will mark the set as finished. Files matching any of the exclude
+// globs will not count towards coverage. An error is returned if the cover
+// profile cannot be read.
+func (set *Set) Complete(shouldCover bool, coverProfile string, excludes ...string) error {
+	defer set.stopAtEnd()
+	end := set.end()
 	if set.State != Fail {
 		set.State = Pass
 	}
+	// tests and packages that never finished, like when the output was cut off,
+	// fail so that an incomplete run can never pass
 	for _, pkg := range set.Packages {
 		for _, test := range pkg.Tests {
-			if test.State == Continue || test.State == Pause || test.State == Run {
+			test.stop(end)
+			if unfinished(test.State) {
 				test.State = Fail
+				pkg.Fail++
+				set.TestSummary.Fail++
+				set.FailedTests = append(set.FailedTests, test)
+				set.State = Fail
 			}
 			if test.State == Fail {
 				for _, fail := range test.Failures {
@@ -121,43 +269,104 @@ func (set *Set) Complete(shouldCover bool, coverProfile string) {
 				}
 			}
 		}
+		pkg.stop(end)
+		if unfinished(pkg.State) {
+			pkg.State = Fail
+			set.PkgSummary.Fail++
+			set.State = Fail
+		}
 	}
 	sort.Slice(set.SlowTests, func(i, j int) bool {
 		return set.SlowTests[i].Elapsed() > set.SlowTests[j].Elapsed()
 	})
 	if shouldCover {
-		set.parseCoverProfile(coverProfile)
+		return set.parseCoverProfile(coverProfile, excludes)
 	}
+	return nil
+}
+
+func unfinished(state Action) bool {
+	return state == Run || state == Continue || state == Pause
 }
 
 // Add adds an event line to the setult set
 func (set *Set) Add(action Action, pkgName, testName, output string) {
-	if _, ok := set.Packages[pkgName]; !ok {
-		set.Packages[pkgName] = newPackage(pkgName)
+	set.addEvent(&logLine{Action: action, Package: pkgName, Test: testName, Output: output})
+}
+
+func (set *Set) addEvent(line *logLine) {
+	set.event = line
+	defer func() { set.event = nil }()
+	if !line.Time.IsZero() {
+		if set.firstEvent.IsZero() {
+			set.firstEvent = line.Time
+		}
+		set.lastEvent = line.Time
+	}
+	if _, ok := set.Packages[line.Package]; !ok {
+		set.Packages[line.Package] = newPackage(line.Package, set.now())
 	}
-	pkg := set.Packages[pkgName]
-	if _, ok := pkg.Tests[testName]; testName != "" && !ok {
+	pkg := set.Packages[line.Package]
+	if _, ok := pkg.Tests[line.Test]; line.Test != "" && !ok {
 		set.TotalTests++
-		pkg.Tests[testName] = newTest(pkg.Name, testName)
+		pkg.Tests[line.Test] = newTest(pkg.Name, line.Test, set.now())
 	}
-	if testName == "" {
-		pkg.result(set, action, output)
+	if line.Test == "" {
+		pkg.result(set, line.Action, line.Output)
 	} else {
-		pkg.Tests[testName].result(set, pkg, action, output)
+		pkg.Tests[line.Test].result(set, pkg, line.Action, line.Output)
+	}
+}
+
+// now is the time of the event being added, og's own clock is only used if go
+// test did not say when the event happened.
+func (set *Set) now() time.Time {
+	if set.event != nil && !set.event.Time.IsZero() {
+		return set.event.Time
 	}
+	return time.Now()
+}
+
+// eventElapsed is how long go test said the test or package took, if the event
+// being added has it
+func (set *Set) eventElapsed() *float64 {
+	if set.event == nil {
+		return nil
+	}
+	return set.event.Elapsed
+}
+
+// end is when the set stopped running. A replayed set ended at its last event.
+func (set *Set) end() time.Time {
+	if !set.replay || set.firstEvent.IsZero() {
+		return time.Now()
+	}
+	return set.lastEvent
+}
+
+// stopAtEnd stops the set. A replayed set ran from its first to its last event.
+func (set *Set) stopAtEnd() {
+	set.stop(set.end())
+	if !set.replay || set.firstEvent.IsZero() {
+		return
+	}
+	set.Total = set.lastEvent.Sub(set.firstEvent)
+	set.Intervals = []*Interval{{Start: set.firstEvent, Stop: set.lastEvent}}
 }
 
 // ParseError will try its best to parse an error message for formatting
 // to the output, adding excerpts and diffs where possible
 func (set *Set) ParseError(bdata []byte) {
 	data := string(bdata)
-	if strings.HasPrefix(strings.TrimSpace(data), "have (") {
+	// have and want lines belong to the error before them, without one they are
+	// kept as a message of their own
+	if len(set.BuildErrors) > 0 && strings.HasPrefix(strings.TrimSpace(data), "have (") {
 		builderr := set.BuildErrors[len(set.BuildErrors)-1]
-		builderr.Have = strings.TrimSpace(strings.TrimPrefix(data, "have "))
+		builderr.Have = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(data), "have "))
 		return
-	} else if strings.HasPrefix(strings.TrimSpace(data), "want (") {
+	} else if len(set.BuildErrors) > 0 && strings.HasPrefix(strings.TrimSpace(data), "want (") {
 		builderr := set.BuildErrors[len(set.BuildErrors)-1]
-		builderr.Want = strings.TrimSpace(strings.TrimPrefix(data, "want "))
+		builderr.Want = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(data), "want "))
 		return
 	}
 	builderr := &BuildError{Raw: data}
@@ -187,30 +396,13 @@ func (set *Set) ParseError(bdata []byte) {
 	set.BuildErrors = append(set.BuildErrors, builderr)
 }
 
-func (set *Set) parseCoverProfile(coverPath string) {
-	rd, err := os.Open(coverPath)
-	if err != nil {
-		return // just bail out if there is no file
-	}
-	defer rd.Close()
-	s := bufio.NewScanner(rd)
-	for s.Scan() {
-		line := s.Text()
-		if strings.HasPrefix(line, "mode: ") {
-			continue
-		}
-		matches := coverLinePat.FindStringSubmatch(line)
-		pkg := set.Packages[filepath.Dir(matches[1])]
-		stmts := atoi(matches[6])
-		pkg.StatementCount += stmts
-		set.StatementCount += stmts
-		if atoi(matches[7]) > 0 {
-			pkg.CoveredCount += stmts
-			set.CoveredCount += stmts
+func (set *Set) hasBuildError(raw string) bool {
+	for _, builderr := range set.BuildErrors {
+		if builderr.Raw == raw {
+			return true
 		}
-		pkg.CoveragePercent = calcPercent(pkg.StatementCount, pkg.CoveredCount)
 	}
-	set.CoveragePercent = calcPercent(set.StatementCount, set.CoveredCount)
+	return false
 }
 
 func calcPercent(statements, covered int64) float64 {
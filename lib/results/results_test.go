@@ -1,6 +1,7 @@
 package results
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -28,6 +29,69 @@ func TestSetParse(t *testing.T) {
 		assert.Equal(t, set.Packages["github.com/tanema/og/nope"].Tests["TestA"].State, Pass)
 		assert.Empty(t, set.BuildErrors)
 	})
+	t.Run("json event times", func(t *testing.T) {
+		set := New("", 10*time.Minute)
+		set.Replay()
+		set.Parse([]byte(`{"Time":"2022-01-01T00:00:00Z","Action":"run","Package":"nope","Test":"TestA"}`))
+		set.Parse([]byte(`{"Time":"2022-01-01T00:00:01Z","Action":"pause","Package":"nope","Test":"TestA"}`))
+		set.Parse([]byte(`{"Time":"2022-01-01T00:00:03Z","Action":"cont","Package":"nope","Test":"TestA"}`))
+		set.Parse([]byte(`{"Time":"2022-01-01T00:00:04Z","Action":"pass","Package":"nope","Test":"TestA","Elapsed":1.5}`))
+		set.Parse([]byte(`{"Time":"2022-01-01T00:00:05Z","Action":"pass","Package":"nope","Elapsed":4.2}`))
+		set.Complete(false, "")
+		test := set.Packages["nope"].Tests["TestA"]
+		assert.Equal(t, 1500*time.Millisecond, test.Elapsed())
+		assert.Equal(t, 2, len(test.Intervals))
+		assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 3, 0, time.UTC), test.Intervals[1].Start)
+		assert.Equal(t, 4200*time.Millisecond, set.Packages["nope"].Elapsed())
+		assert.Equal(t, 5*time.Second, set.Elapsed())
+	})
+	t.Run("non json output", func(t *testing.T) {
+		set := New("", 10*time.Minute)
+		set.Parse([]byte(""))
+		set.Parse([]byte("../../_testdata/go.go:8:2: undefined: x"))
+		assert.Empty(t, set.Packages)
+		assert.Equal(t, 1, len(set.BuildErrors))
+		assert.Equal(t, int64(8), set.BuildErrors[0].Line)
+	})
+	t.Run("have and want without a build error", func(t *testing.T) {
+		set := New("", 10*time.Minute)
+		set.Parse([]byte("\thave (int)"))
+		assert.Equal(t, 1, len(set.BuildErrors))
+		assert.Equal(t, "\thave (int)", set.BuildErrors[0].Message)
+		set.Parse([]byte("\twant (string)"))
+		assert.Equal(t, 1, len(set.BuildErrors))
+		assert.Equal(t, "(string)", set.BuildErrors[0].Want)
+	})
+	t.Run("cut off stream", func(t *testing.T) {
+		set := New("", 10*time.Minute)
+		set.Replay()
+		set.Parse([]byte(`{"Time":"2022-01-01T00:00:00Z","Action":"run","Package":"nope","Test":"TestA"}`))
+		set.Parse([]byte(`{"Time":"2022-01-01T00:00:02Z","Action":"output","Package":"nope","Test":"TestA","Output":"working\n"}`))
+		set.Complete(false, "")
+		test := set.Packages["nope"].Tests["TestA"]
+		assert.Equal(t, 2*time.Second, test.Elapsed())
+		assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 2, 0, time.UTC), test.Intervals[0].Stop)
+		assert.Equal(t, 2*time.Second, set.Packages["nope"].Elapsed())
+		assert.Equal(t, Fail, set.State)
+		assert.Equal(t, Fail, set.Packages["nope"].State)
+		assert.Equal(t, Fail, set.Packages["nope"].Tests["TestA"].State)
+		assert.Equal(t, 1, set.TestSummary.Fail)
+		assert.Equal(t, 1, set.PkgSummary.Fail)
+		assert.Equal(t, 1, len(set.FailedTests))
+	})
+	t.Run("json build events", func(t *testing.T) {
+		set := New("", 10*time.Minute)
+		set.Parse([]byte(`{"ImportPath":"nope [nope.test]","Action":"build-output","Output":"# nope [nope.test]\n"}`))
+		set.Parse([]byte(`{"ImportPath":"nope [nope.test]","Action":"build-output","Output":"../../_testdata/go.go:8:2: undefined: x\n"}`))
+		set.Parse([]byte(`{"ImportPath":"nope [nope.test]","Action":"build-fail"}`))
+		set.Parse([]byte(`{"ImportPath":"nope","Action":"build-output","Output":"# nope\n"}`))
+		set.Parse([]byte(`{"ImportPath":"nope","Action":"build-output","Output":"../../_testdata/go.go:8:2: undefined: x\n"}`))
+		assert.Equal(t, Fail, set.State)
+		assert.Empty(t, set.Packages)
+		assert.Equal(t, 1, len(set.BuildErrors))
+		assert.Equal(t, "../../_testdata/go.go", set.BuildErrors[0].Path)
+		assert.Equal(t, int64(8), set.BuildErrors[0].Line)
+	})
 }
 
 func TestSetAdd(t *testing.T) {
@@ -50,3 +114,56 @@ func TestSetAdd(t *testing.T) {
 		assert.Equal(t, 1, set.TotalTests)
 	})
 }
+
+func TestSetJSON(t *testing.T) {
+	set := New("", 10*time.Minute)
+	set.Add(Run, "github.com/tanema/og/nope", "TestA", "")
+	set.Add(Fail, "github.com/tanema/og/nope", "TestA", "")
+	set.Add(Fail, "github.com/tanema/og/nope", "", "")
+	set.Complete(false, "")
+
+	data, err := json.Marshal(set)
+	assert.Nil(t, err)
+
+	loaded := &Set{}
+	assert.Nil(t, json.Unmarshal(data, loaded))
+	assert.Equal(t, set.Elapsed(), loaded.Elapsed())
+	assert.Equal(t, Fail, loaded.State)
+	pkg := loaded.Packages["github.com/tanema/og/nope"]
+	assert.NotNil(t, pkg)
+	assert.Equal(t, set.Packages["github.com/tanema/og/nope"].Elapsed(), pkg.Elapsed())
+	assert.Equal(t, set.Packages["github.com/tanema/og/nope"].Tests["TestA"].Elapsed(), pkg.Tests["TestA"].Elapsed())
+	assert.Equal(t, 1, len(loaded.FailedTests))
+	assert.Equal(t, "TestA", loaded.FailedTests[0].Name)
+	assert.Equal(t, 10*time.Minute, loaded.threshold)
+	assert.Contains(t, string(data), `"version":1`)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"state":"pass"}`), &Set{}))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"version":99,"state":"pass"}`), &Set{}))
+}
+
+func TestSetCompact(t *testing.T) {
+	set := New("", 10*time.Minute)
+	set.Add(Run, "github.com/tanema/og/nope", "TestA", "")
+	set.Add(Fail, "github.com/tanema/og/nope", "TestA", "")
+	set.Add(Fail, "github.com/tanema/og/nope", "", "")
+	set.Complete(false, "")
+	set.Packages["github.com/tanema/og/nope"].CoverBlocks = []*CoverBlock{{File: "a.go", NumStmt: 1}}
+
+	compact := set.Compact()
+	pkg := compact.Packages["github.com/tanema/og/nope"]
+	assert.Empty(t, pkg.CoverBlocks)
+	assert.Empty(t, pkg.Intervals)
+	assert.Empty(t, pkg.Tests["TestA"].Intervals)
+	assert.Equal(t, set.Packages["github.com/tanema/og/nope"].Elapsed(), pkg.Elapsed())
+	assert.Same(t, pkg.Tests["TestA"], compact.FailedTests[0])
+
+	data, err := json.Marshal(compact)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "cover_blocks")
+	assert.NotContains(t, string(data), "intervals")
+
+	// the set itself is left as it was
+	assert.Equal(t, 1, len(set.Packages["github.com/tanema/og/nope"].CoverBlocks))
+	assert.NotEmpty(t, set.Packages["github.com/tanema/og/nope"].Tests["TestA"].Intervals)
+}
@@ -2,47 +2,85 @@ package results
 
 import "time"
 
-type stopwatch struct {
-	started time.Time
-	paused  bool
-	parts   []time.Duration
-	total   time.Duration
-}
+type (
+	stopwatch struct {
+		Total     time.Duration `json:"elapsed,omitempty"`
+		Intervals []*Interval   `json:"intervals,omitempty"`
+		started   time.Time
+		paused    bool
+	}
+	// Interval is a span of time that a package or test was running. A test
+	// that calls t.Parallel will have an interval before and after it paused.
+	Interval struct {
+		Start time.Time `json:"start"`
+		Stop  time.Time `json:"stop"`
+	}
+)
 
-func (watch *stopwatch) start() {
+func (watch *stopwatch) start(at time.Time) {
 	if watch.paused {
-		watch.started = time.Now()
+		watch.started = at
 		watch.paused = false
+		watch.Intervals = append(watch.Intervals, &Interval{Start: watch.started})
 	} else if watch.started.IsZero() {
-		watch.total = 0
-		watch.started = time.Now()
+		watch.Total = 0
+		watch.started = at
 		watch.paused = false
+		watch.Intervals = append(watch.Intervals, &Interval{Start: watch.started})
 	}
 }
 
+// compact copies the stopwatch without its intervals
+func (watch *stopwatch) compact() *stopwatch {
+	if watch == nil {
+		return nil
+	}
+	compact := *watch
+	compact.Intervals = nil
+	return &compact
+}
+
 func (watch *stopwatch) running() bool {
 	return !watch.paused && !watch.started.IsZero()
 }
 
 func (watch *stopwatch) Elapsed() time.Duration {
 	if !watch.running() {
-		return watch.total.Round(time.Millisecond)
+		return watch.Total.Round(time.Millisecond)
 	}
-	return (watch.total + time.Since(watch.started)).Round(time.Millisecond)
+	return (watch.Total + time.Since(watch.started)).Round(time.Millisecond)
 }
 
-func (watch *stopwatch) pause() time.Duration {
+func (watch *stopwatch) pause(at time.Time) time.Duration {
 	if !watch.paused {
-		watch.total += time.Since(watch.started)
+		watch.Total += at.Sub(watch.started)
 		watch.paused = true
+		watch.closeInterval(at)
 	}
 	return watch.Elapsed()
 }
 
-func (watch *stopwatch) stop() time.Duration {
+func (watch *stopwatch) stop(at time.Time) time.Duration {
 	if watch.running() {
-		watch.total += time.Since(watch.started)
+		watch.Total += at.Sub(watch.started)
 		watch.started = time.Time{}
+		watch.closeInterval(at)
 	}
 	return watch.Elapsed()
 }
+
+// finish stops the watch, if go test reported how long it took that is used
+// instead of the time measured by the watch.
+func (watch *stopwatch) finish(at time.Time, elapsed *float64) time.Duration {
+	watch.stop(at)
+	if elapsed != nil {
+		watch.Total = time.Duration(*elapsed * float64(time.Second))
+	}
+	return watch.Elapsed()
+}
+
+func (watch *stopwatch) closeInterval(at time.Time) {
+	if len(watch.Intervals) > 0 {
+		watch.Intervals[len(watch.Intervals)-1].Stop = at
+	}
+}
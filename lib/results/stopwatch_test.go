@@ -2,6 +2,7 @@ package results
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -12,24 +13,42 @@ func TestStopwatch(t *testing.T) {
 	assert.False(t, watch.paused)
 	assert.False(t, watch.running())
 
-	watch.start()
+	watch.start(time.Now())
 	assert.False(t, watch.started.IsZero())
 	assert.False(t, watch.paused)
 	assert.True(t, watch.running())
 
-	watch.pause()
+	watch.pause(time.Now())
 	assert.True(t, watch.paused)
 	assert.False(t, watch.running())
 
-	watch.start()
+	watch.start(time.Now())
 	assert.True(t, watch.running())
 	assert.False(t, watch.paused)
 
-	watch.start()
+	watch.start(time.Now())
 	assert.False(t, watch.paused)
 	assert.True(t, watch.running())
 
-	watch.stop()
+	watch.stop(time.Now())
 	assert.False(t, watch.paused)
 	assert.False(t, watch.running())
+	assert.Equal(t, 2, len(watch.Intervals))
+	for _, interval := range watch.Intervals {
+		assert.False(t, interval.Stop.Before(interval.Start))
+	}
+	assert.False(t, watch.Intervals[1].Start.Before(watch.Intervals[0].Stop))
+}
+
+func TestStopwatchFinish(t *testing.T) {
+	start := time.Now()
+	watch := &stopwatch{}
+	watch.start(start)
+	assert.Equal(t, 2*time.Second, watch.finish(start.Add(2*time.Second), nil))
+	assert.Equal(t, start.Add(2*time.Second), watch.Intervals[0].Stop)
+
+	elapsed := 0.5
+	watch = &stopwatch{}
+	watch.start(start)
+	assert.Equal(t, 500*time.Millisecond, watch.finish(start.Add(2*time.Second), &elapsed))
 }
@@ -1,10 +1,12 @@
 package results
 
 import (
+	"encoding/json"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type (
@@ -67,17 +69,27 @@ var (
 	testifyTestPattern  = regexp.MustCompile(`^\s*Test:\s*Test[a-zA-Z\/^\s]*`)
 )
 
-func newTest(pkgName, testName string) *Test {
+func newTest(pkgName, testName string, at time.Time) *Test {
 	test := &Test{
 		stopwatch: &stopwatch{},
 		Name:      testName,
 		State:     Run,
 		Package:   pkgName,
 	}
-	test.start()
+	test.start(at)
 	return test
 }
 
+// UnmarshalJSON decodes a test that was previously encoded with json.Marshal
+func (test *Test) UnmarshalJSON(data []byte) error {
+	type plainTest Test
+	plain := (*plainTest)(test)
+	if plain.stopwatch == nil {
+		plain.stopwatch = &stopwatch{}
+	}
+	return json.Unmarshal(data, plain)
+}
+
 func (test *Test) result(set *Set, pkg *Package, action Action, output string) {
 	switch action {
 	case Pass:
@@ -92,9 +104,9 @@ func (test *Test) result(set *Set, pkg *Package, action Action, output string) {
 		set.TestSummary.Skip++
 		set.SkippedTests = append(set.SkippedTests, test)
 	case Run, Continue:
-		test.start()
+		test.start(set.now())
 	case Pause:
-		test.pause()
+		test.pause(set.now())
 	case Output:
 		test.addLogOutput(output)
 	}
@@ -102,7 +114,7 @@ func (test *Test) result(set *Set, pkg *Package, action Action, output string) {
 		test.State = action
 	}
 	if action == Pass || action == Fail || action == Skip {
-		if elapsed := test.stop(); elapsed > set.threshold {
+		if elapsed := test.finish(set.now(), set.eventElapsed()); elapsed > set.threshold {
 			set.SlowTests = append(set.SlowTests, test)
 		}
 	}
@@ -0,0 +1,121 @@
+package results
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// Timeline shows when each package and top level test ran during a set
+	Timeline struct {
+		Start   time.Time      `json:"start"`
+		Elapsed time.Duration  `json:"elapsed"`
+		Rows    []*TimelineRow `json:"rows"`
+	}
+	// TimelineRow is a single package or test in a timeline
+	TimelineRow struct {
+		Package   string        `json:"package"`
+		Name      string        `json:"name,omitempty"`
+		State     Action        `json:"state"`
+		Elapsed   time.Duration `json:"elapsed"`
+		Intervals []*Interval   `json:"intervals"`
+		timeline  *Timeline
+	}
+)
+
+// Timeline collects the intervals that the packages and top level tests ran,
+// ordered by when they started.
+func (set *Set) Timeline() *Timeline {
+	timeline := &Timeline{Rows: []*TimelineRow{}}
+	groups := [][]*TimelineRow{}
+	var end time.Time
+	for _, pkg := range set.Packages {
+		if len(pkg.Intervals) == 0 {
+			continue
+		}
+		tests := []*TimelineRow{}
+		for name, test := range pkg.Tests {
+			if len(test.Intervals) > 0 && !strings.Contains(name, "/") {
+				tests = append(tests, timeline.newRow(pkg.Name, name, test.State, test.stopwatch))
+			}
+		}
+		sortRows(tests)
+		rows := append([]*TimelineRow{timeline.newRow(pkg.Name, "", pkg.State, pkg.stopwatch)}, tests...)
+		for _, row := range rows {
+			for _, interval := range row.Intervals {
+				if timeline.Start.IsZero() || interval.Start.Before(timeline.Start) {
+					timeline.Start = interval.Start
+				}
+				if interval.Stop.After(end) {
+					end = interval.Stop
+				}
+			}
+		}
+		groups = append(groups, rows)
+	}
+	if !end.IsZero() {
+		timeline.Elapsed = end.Sub(timeline.Start).Round(time.Millisecond)
+	}
+	// packages are ordered by when they started, followed by their tests
+	sort.Slice(groups, func(i, j int) bool { return rowBefore(groups[i][0], groups[j][0]) })
+	for _, rows := range groups {
+		timeline.Rows = append(timeline.Rows, rows...)
+	}
+	return timeline
+}
+
+func (timeline *Timeline) newRow(pkg, name string, state Action, watch *stopwatch) *TimelineRow {
+	return &TimelineRow{Package: pkg, Name: name, State: state, Elapsed: watch.Elapsed(), Intervals: watch.Intervals, timeline: timeline}
+}
+
+// Bar draws the row as a bar of width characters spanning the whole timeline.
+// Time spent running is drawn as █ and time spent paused between intervals as ░.
+func (row *TimelineRow) Bar(width int) string {
+	total := row.timeline.Elapsed
+	if total <= 0 || len(row.Intervals) == 0 {
+		return strings.Repeat(" ", width)
+	}
+	cells := []rune(strings.Repeat(" ", width))
+	first, last := width, 0
+	for _, interval := range row.Intervals {
+		start, stop := clamp(row.cell(interval.Start, width), 0, width-1), width
+		if !interval.Stop.IsZero() {
+			stop = clamp(row.cell(interval.Stop, width), start+1, width)
+		}
+		for i := start; i < stop; i++ {
+			cells[i] = '█'
+		}
+		if start < first {
+			first = start
+		}
+		if stop > last {
+			last = stop
+		}
+	}
+	for i := first; i < last; i++ {
+		if cells[i] == ' ' {
+			cells[i] = '░'
+		}
+	}
+	return string(cells)
+}
+
+// cell is the index of the character in a bar of width that time at falls in
+func (row *TimelineRow) cell(at time.Time, width int) int {
+	offset := float64(at.Sub(row.timeline.Start)) / float64(row.timeline.Elapsed)
+	return int(offset * float64(width))
+}
+
+func sortRows(rows []*TimelineRow) {
+	sort.Slice(rows, func(i, j int) bool { return rowBefore(rows[i], rows[j]) })
+}
+
+func rowBefore(a, b *TimelineRow) bool {
+	if !a.Intervals[0].Start.Equal(b.Intervals[0].Start) {
+		return a.Intervals[0].Start.Before(b.Intervals[0].Start)
+	} else if a.Package != b.Package {
+		return a.Package < b.Package
+	}
+	return a.Name < b.Name
+}
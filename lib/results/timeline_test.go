@@ -0,0 +1,60 @@
+package results
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeline(t *testing.T) {
+	start := time.Now()
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	set := New("", time.Minute)
+	set.Add(Run, "b", "TestB", "")
+	set.Add(Run, "a", "TestA", "")
+	set.Add(Run, "a", "TestA/sub", "")
+	set.Packages["a"].Intervals = []*Interval{{Start: at(0), Stop: at(10)}}
+	set.Packages["a"].Tests["TestA"].Intervals = []*Interval{{Start: at(0), Stop: at(2)}, {Start: at(6), Stop: at(10)}}
+	set.Packages["b"].Intervals = []*Interval{{Start: at(5), Stop: at(20)}}
+	set.Packages["b"].Tests["TestB"].Intervals = []*Interval{{Start: at(5), Stop: at(20)}}
+
+	timeline := set.Timeline()
+	assert.Equal(t, at(0), timeline.Start)
+	assert.Equal(t, 20*time.Second, timeline.Elapsed)
+	assert.Equal(t, 4, len(timeline.Rows))
+	assert.Equal(t, "a", timeline.Rows[0].Package)
+	assert.Equal(t, "", timeline.Rows[0].Name)
+	assert.Equal(t, "TestA", timeline.Rows[1].Name)
+	assert.Equal(t, "b", timeline.Rows[2].Package)
+	assert.Equal(t, "TestB", timeline.Rows[3].Name)
+
+	assert.Equal(t, "██░░░░████          ", timeline.Rows[1].Bar(20))
+	assert.Equal(t, "     ███████████████", timeline.Rows[3].Bar(20))
+	assert.Equal(t, "          ", (&TimelineRow{timeline: &Timeline{}}).Bar(10))
+}
+
+func TestTimelineJSON(t *testing.T) {
+	set := New("", time.Minute)
+	set.Add(Run, "a", "TestA", "")
+	set.Add(Pass, "a", "TestA", "")
+	set.Add(Pass, "a", "", "")
+	set.Complete(false, "")
+
+	data, err := json.Marshal(set)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), `"timeline"`)
+
+	set.IncludeTimeline()
+	data, err = json.Marshal(set)
+	assert.Nil(t, err)
+	dump := struct {
+		Timeline *Timeline `json:"timeline"`
+	}{}
+	assert.Nil(t, json.Unmarshal(data, &dump))
+	assert.Equal(t, 2, len(dump.Timeline.Rows))
+	assert.Equal(t, "TestA", dump.Timeline.Rows[1].Name)
+
+	assert.Nil(t, json.Unmarshal(data, &Set{}))
+}
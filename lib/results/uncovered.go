@@ -0,0 +1,173 @@
+package results
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+type (
+	// UncoveredFile is a source file that has statements that were never run
+	UncoveredFile struct {
+		Name  string           `json:"name"`
+		Path  string           `json:"path"`
+		Funcs []*UncoveredFunc `json:"funcs"`
+	}
+	// UncoveredFunc groups the uncovered blocks in a single function
+	UncoveredFunc struct {
+		Name   string            `json:"name"`
+		Blocks []*UncoveredBlock `json:"blocks"`
+	}
+	// UncoveredBlock is a single block of statements that was never run, with
+	// an excerpt of the code it covers
+	UncoveredBlock struct {
+		Line       int64                   `json:"line"`
+		Column     int64                   `json:"column"`
+		EndLine    int64                   `json:"end_line"`
+		EndColumn  int64                   `json:"end_column"`
+		Statements int64                   `json:"statements"`
+		Before     *ExcerptLine            `json:"-"`
+		Lines      []*ExcerptHighlightLine `json:"-"`
+		After      *ExcerptLine            `json:"-"`
+	}
+)
+
+// Uncovered reads a cover profile and collects all of the blocks that were not
+// run, grouped by file and function. Blocks excluded from coverage are left out.
+// Filters can be package directories, go files or import paths. Package
+// directories ending in /... will match all sub packages.
+func Uncovered(coverPath string, excludes []string, filters ...string) ([]*UncoveredFile, error) {
+	coverFiles, err := loadCoverProfile(coverPath, excludes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read cover profile: %v", err)
+	}
+	files := []*UncoveredFile{}
+	for _, coverFile := range coverFiles {
+		if coverFile.ast == nil || !matchesFilters(coverFile.FileName, coverFile.Path, filters) {
+			continue
+		}
+		if file := newUncoveredFile(coverFile); len(file.Funcs) > 0 {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func matchesFilters(name, localPath string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		recursive := strings.HasSuffix(filter, "/...")
+		filter = strings.TrimSuffix(filter, "/...")
+		if filter == name || path.Dir(name) == filter || (recursive && strings.HasPrefix(name, filter+"/")) {
+			return true
+		}
+		abs, err := filepath.Abs(filter)
+		if err != nil {
+			continue
+		}
+		local, _ := filepath.Abs(localPath)
+		if abs == local || filepath.Dir(local) == abs || (recursive && strings.HasPrefix(local, abs+string(filepath.Separator))) {
+			return true
+		}
+	}
+	return false
+}
+
+func newUncoveredFile(coverFile *coverFile) *UncoveredFile {
+	lines := strings.Split(string(coverFile.src), "\n")
+	file := &UncoveredFile{Name: coverFile.FileName, Path: coverFile.Path, Funcs: []*UncoveredFunc{}}
+	funcs := map[string]*UncoveredFunc{}
+	for _, block := range coverFile.Included {
+		if block.Count > 0 {
+			continue
+		}
+		name := funcName(coverFile.fset, coverFile.ast, block.StartLine)
+		if _, ok := funcs[name]; !ok {
+			funcs[name] = &UncoveredFunc{Name: name}
+			file.Funcs = append(file.Funcs, funcs[name])
+		}
+		funcs[name].Blocks = append(funcs[name].Blocks, newUncoveredBlock(block, lines))
+	}
+	return file
+}
+
+func newUncoveredBlock(block cover.ProfileBlock, lines []string) *UncoveredBlock {
+	uncovered := &UncoveredBlock{
+		Line:       int64(block.StartLine),
+		Column:     int64(block.StartCol),
+		EndLine:    int64(block.EndLine),
+		EndColumn:  int64(block.EndCol),
+		Statements: int64(block.NumStmt),
+	}
+	digitCount := digits(uncovered.EndLine + 1)
+	if before := block.StartLine - 1; before > 0 && before <= len(lines) {
+		uncovered.Before = &ExcerptLine{Line: leftPad(int64(before), digitCount), Code: detab(lines[before-1])}
+	}
+	for num := block.StartLine; num <= block.EndLine && num <= len(lines); num++ {
+		text := lines[num-1]
+		start, end := 0, len(text)
+		if num == block.StartLine {
+			start = clamp(block.StartCol-1, 0, len(text))
+		}
+		if num == block.EndLine {
+			end = clamp(block.EndCol-1, start, len(text))
+		}
+		uncovered.Lines = append(uncovered.Lines, &ExcerptHighlightLine{
+			Line:      leftPad(int64(num), digitCount),
+			Prefix:    detab(text[:start]),
+			Highlight: detab(text[start:end]),
+			Suffix:    detab(text[end:]),
+		})
+	}
+	if after := block.EndLine + 1; after <= len(lines) {
+		uncovered.After = &ExcerptLine{Line: leftPad(int64(after), digitCount), Code: detab(lines[after-1])}
+	}
+	return uncovered
+}
+
+// funcName finds the name of the function declaration that contains line
+func funcName(fset *token.FileSet, file *ast.File, line int) string {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || line < fset.Position(fn.Pos()).Line || line > fset.Position(fn.End()).Line {
+			continue
+		}
+		if fn.Recv == nil || len(fn.Recv.List) == 0 {
+			return fn.Name.Name
+		}
+		return fmt.Sprintf("(%v).%v", recvName(fn.Recv.List[0].Type), fn.Name.Name)
+	}
+	return "(package)"
+}
+
+func recvName(expr ast.Expr) string {
+	switch typ := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + recvName(typ.X)
+	case *ast.IndexExpr:
+		return recvName(typ.X)
+	case *ast.Ident:
+		return typ.Name
+	}
+	return ""
+}
+
+func detab(str string) string {
+	return strings.ReplaceAll(str, "\t", "  ")
+}
+
+func clamp(val, low, high int) int {
+	if val < low {
+		return low
+	} else if val > high {
+		return high
+	}
+	return val
+}
@@ -0,0 +1,53 @@
+package results
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const uncoveredProfile = `mode: set
+github.com/tanema/og/_testdata/go.go:3.24,5.2 1 1
+github.com/tanema/og/_testdata/go.go:7.29,9.2 1 0
+github.com/tanema/og/_testdata/go.go:11.27,13.2 1 0
+`
+
+func TestUncovered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cover.out")
+	assert.Nil(t, os.WriteFile(path, []byte(uncoveredProfile), 0644))
+
+	files, err := Uncovered(path, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, "../../_testdata/go.go", files[0].Path)
+	assert.Equal(t, 2, len(files[0].Funcs))
+	assert.Equal(t, "subtract", files[0].Funcs[0].Name)
+	assert.Equal(t, "divide", files[0].Funcs[1].Name)
+
+	block := files[0].Funcs[0].Blocks[0]
+	assert.Equal(t, int64(7), block.Line)
+	assert.Equal(t, &ExcerptLine{Line: " 6", Code: ""}, block.Before)
+	assert.Equal(t, []*ExcerptHighlightLine{
+		{Line: " 7", Prefix: "func subtract(a, b int) int ", Highlight: "{", Suffix: ""},
+		{Line: " 8", Prefix: "", Highlight: "  return a - b", Suffix: ""},
+		{Line: " 9", Prefix: "", Highlight: "}", Suffix: ""},
+	}, block.Lines)
+	assert.Equal(t, &ExcerptLine{Line: "10", Code: ""}, block.After)
+
+	files, err = Uncovered(path, nil, "../../_testdata/go.go")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+
+	files, err = Uncovered(path, nil, "github.com/tanema/og/_testdata")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+
+	files, err = Uncovered(path, nil, "./")
+	assert.Nil(t, err)
+	assert.Empty(t, files)
+
+	_, err = Uncovered(filepath.Join(t.TempDir(), "nope.out"), nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,226 @@
+package shard
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Shard is one part of the tests when they are split across machines.
+	// Index starts at 1.
+	Shard struct {
+		Index int
+		Total int
+	}
+	// Package is a package that has tests, along with its top level tests
+	Package struct {
+		Path  string
+		Tests []string
+	}
+	// Unit is a whole package, or some of the tests in a package, that runs in a
+	// single shard
+	Unit struct {
+		Package string
+		Tests   []string
+		Weight  time.Duration
+	}
+	// Durations are how long packages and tests took in earlier runs
+	Durations struct {
+		Packages map[string]time.Duration
+		Tests    map[string]map[string]time.Duration
+	}
+)
+
+// Parse reads a shard in the form index/total, like 2/5
+func Parse(str string) (Shard, error) {
+	parts := strings.Split(str, "/")
+	if len(parts) != 2 {
+		return Shard{}, fmt.Errorf("shard %q should be in the form index/total", str)
+	}
+	index, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return Shard{}, fmt.Errorf("invalid shard index %q", parts[0])
+	}
+	total, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Shard{}, fmt.Errorf("invalid shard total %q", parts[1])
+	}
+	if total < 1 || index < 1 || index > total {
+		return Shard{}, fmt.Errorf("shard %q should be between 1/%v and %v/%v", str, total, total, total)
+	}
+	return Shard{Index: index, Total: total}, nil
+}
+
+func (shard Shard) String() string {
+	return fmt.Sprintf("%v/%v", shard.Index, shard.Total)
+}
+
+// NewDurations creates an empty set of durations
+func NewDurations() *Durations {
+	return &Durations{
+		Packages: map[string]time.Duration{},
+		Tests:    map[string]map[string]time.Duration{},
+	}
+}
+
+// Add records how long a package took, or a test in it if test is not empty.
+// Only the first duration is kept so the most recent runs should be added first.
+func (durations *Durations) Add(pkg, test string, elapsed time.Duration) {
+	if test == "" {
+		if _, ok := durations.Packages[pkg]; !ok {
+			durations.Packages[pkg] = elapsed
+		}
+		return
+	}
+	if _, ok := durations.Tests[pkg]; !ok {
+		durations.Tests[pkg] = map[string]time.Duration{}
+	}
+	if _, ok := durations.Tests[pkg][test]; !ok {
+		durations.Tests[pkg][test] = elapsed
+	}
+}
+
+// Plan picks the packages, and tests, that this shard should run. Every shard
+// will pick the same plan for the same packages and durations. Units are
+// balanced by their expected time, and packages that would take longer than a
+// fair share on their own are split up by test if splitTests is true. Without
+// any durations the packages are split up by a hash of their path.
+func (shard Shard) Plan(pkgs []Package, durations *Durations, splitTests bool) []Unit {
+	units, total, known := packageUnits(pkgs, durations)
+	if known == 0 {
+		return shard.hashed(units)
+	}
+	if splitTests {
+		units = splitLarge(units, pkgs, durations, total/time.Duration(shard.Total))
+	}
+	sort.SliceStable(units, func(i, j int) bool {
+		if units[i].Weight != units[j].Weight {
+			return units[i].Weight > units[j].Weight
+		}
+		return units[i].name() < units[j].name()
+	})
+	loads := make([]time.Duration, shard.Total)
+	picked := []Unit{}
+	for _, unit := range units {
+		lightest := 0
+		for i, load := range loads {
+			if load < loads[lightest] {
+				lightest = i
+			}
+		}
+		loads[lightest] += unit.Weight
+		if lightest == shard.Index-1 {
+			picked = append(picked, unit)
+		}
+	}
+	return group(picked)
+}
+
+// packageUnits makes a unit for each package, packages without a known
+// duration are expected to take the average time of the known packages.
+func packageUnits(pkgs []Package, durations *Durations) ([]Unit, time.Duration, int) {
+	units := []Unit{}
+	var total time.Duration
+	known := 0
+	for _, pkg := range pkgs {
+		weight, ok := durations.Packages[pkg.Path]
+		if !ok {
+			for _, elapsed := range durations.Tests[pkg.Path] {
+				weight += elapsed
+				ok = true
+			}
+		}
+		if ok {
+			known++
+			total += weight
+		}
+		units = append(units, Unit{Package: pkg.Path, Weight: weight})
+	}
+	if known == 0 {
+		return units, 0, 0
+	}
+	average := total / time.Duration(known)
+	for i, pkg := range pkgs {
+		if _, ok := durations.Packages[pkg.Path]; !ok && len(durations.Tests[pkg.Path]) == 0 {
+			units[i].Weight = average
+			total += average
+		}
+	}
+	return units, total, known
+}
+
+// splitLarge replaces packages that are expected to take longer than share
+// with a unit for each of their tests.
+func splitLarge(units []Unit, pkgs []Package, durations *Durations, share time.Duration) []Unit {
+	split := []Unit{}
+	for i, unit := range units {
+		tests := pkgs[i].Tests
+		if unit.Weight <= share || len(tests) < 2 {
+			split = append(split, unit)
+			continue
+		}
+		var knownTotal time.Duration
+		unknown := 0
+		for _, test := range tests {
+			if elapsed, ok := durations.Tests[unit.Package][test]; ok {
+				knownTotal += elapsed
+			} else {
+				unknown++
+			}
+		}
+		var fallback time.Duration
+		if unknown > 0 && unit.Weight > knownTotal {
+			fallback = (unit.Weight - knownTotal) / time.Duration(unknown)
+		}
+		for _, test := range tests {
+			weight, ok := durations.Tests[unit.Package][test]
+			if !ok {
+				weight = fallback
+			}
+			split = append(split, Unit{Package: unit.Package, Tests: []string{test}, Weight: weight})
+		}
+	}
+	return split
+}
+
+func (shard Shard) hashed(units []Unit) []Unit {
+	picked := []Unit{}
+	for _, unit := range units {
+		hash := fnv.New32a()
+		hash.Write([]byte(unit.Package))
+		if int(hash.Sum32()%uint32(shard.Total)) == shard.Index-1 {
+			picked = append(picked, unit)
+		}
+	}
+	return picked
+}
+
+// group combines the tests of a package that were picked for the same shard
+// back into a single unit, in order of package path.
+func group(units []Unit) []Unit {
+	grouped := []Unit{}
+	byPkg := map[string]int{}
+	for _, unit := range units {
+		i, ok := byPkg[unit.Package]
+		if !ok {
+			byPkg[unit.Package] = len(grouped)
+			grouped = append(grouped, unit)
+			continue
+		}
+		grouped[i].Weight += unit.Weight
+		grouped[i].Tests = append(grouped[i].Tests, unit.Tests...)
+	}
+	sort.Slice(grouped, func(i, j int) bool { return grouped[i].Package < grouped[j].Package })
+	for _, unit := range grouped {
+		sort.Strings(unit.Tests)
+	}
+	return grouped
+}
+
+func (unit Unit) name() string {
+	return unit.Package + "#" + strings.Join(unit.Tests, "|")
+}
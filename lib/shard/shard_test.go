@@ -0,0 +1,89 @@
+package shard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	shard, err := Parse("2/5")
+	assert.Nil(t, err)
+	assert.Equal(t, Shard{Index: 2, Total: 5}, shard)
+	assert.Equal(t, "2/5", shard.String())
+
+	for _, bad := range []string{"", "2", "a/5", "2/b", "0/5", "6/5", "1/0"} {
+		_, err := Parse(bad)
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func TestDurationsAdd(t *testing.T) {
+	durations := NewDurations()
+	durations.Add("a", "", time.Second)
+	durations.Add("a", "", time.Minute)
+	durations.Add("a", "TestA", time.Second)
+	durations.Add("a", "TestA", time.Minute)
+	assert.Equal(t, time.Second, durations.Packages["a"])
+	assert.Equal(t, time.Second, durations.Tests["a"]["TestA"])
+}
+
+func TestPlan(t *testing.T) {
+	pkgs := []Package{
+		{Path: "a", Tests: []string{"TestA1", "TestA2"}},
+		{Path: "b", Tests: []string{"TestB"}},
+		{Path: "c", Tests: []string{"TestC"}},
+		{Path: "d", Tests: []string{"TestD"}},
+	}
+
+	t.Run("balanced by duration", func(t *testing.T) {
+		durations := NewDurations()
+		durations.Add("a", "", 4*time.Second)
+		durations.Add("b", "", 3*time.Second)
+		durations.Add("c", "", 2*time.Second)
+		durations.Add("d", "", 1*time.Second)
+		first := Shard{Index: 1, Total: 2}.Plan(pkgs, durations, false)
+		second := Shard{Index: 2, Total: 2}.Plan(pkgs, durations, false)
+		assert.Equal(t, []Unit{{Package: "a", Weight: 4 * time.Second}, {Package: "d", Weight: time.Second}}, first)
+		assert.Equal(t, []Unit{{Package: "b", Weight: 3 * time.Second}, {Package: "c", Weight: 2 * time.Second}}, second)
+	})
+
+	t.Run("unknown packages take the average", func(t *testing.T) {
+		durations := NewDurations()
+		durations.Add("a", "", 4*time.Second)
+		durations.Add("b", "", 2*time.Second)
+		units, total, known := packageUnits(pkgs, durations)
+		assert.Equal(t, 2, known)
+		assert.Equal(t, 12*time.Second, total)
+		assert.Equal(t, 3*time.Second, units[2].Weight)
+	})
+
+	t.Run("large packages are split by test", func(t *testing.T) {
+		durations := NewDurations()
+		durations.Add("a", "", 10*time.Second)
+		durations.Add("a", "TestA1", 6*time.Second)
+		durations.Add("a", "TestA2", 4*time.Second)
+		durations.Add("b", "", time.Second)
+		durations.Add("c", "", time.Second)
+		durations.Add("d", "", time.Second)
+		first := Shard{Index: 1, Total: 2}.Plan(pkgs, durations, true)
+		second := Shard{Index: 2, Total: 2}.Plan(pkgs, durations, true)
+		assert.Equal(t, []Unit{{Package: "a", Tests: []string{"TestA1"}, Weight: 6 * time.Second}, {Package: "d", Weight: time.Second}}, first)
+		assert.Equal(t, 3, len(second))
+		assert.Equal(t, []string{"TestA2"}, second[0].Tests)
+	})
+
+	t.Run("hash fallback", func(t *testing.T) {
+		seen := map[string]int{}
+		for i := 1; i <= 3; i++ {
+			units := Shard{Index: i, Total: 3}.Plan(pkgs, NewDurations(), true)
+			assert.Equal(t, units, Shard{Index: i, Total: 3}.Plan(pkgs, NewDurations(), true))
+			for _, unit := range units {
+				assert.Empty(t, unit.Tests)
+				seen[unit.Package]++
+			}
+		}
+		assert.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1, "d": 1}, seen)
+	})
+}
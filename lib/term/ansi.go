@@ -2,6 +2,7 @@ package term
 
 import (
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
 	"text/template"
@@ -10,15 +11,19 @@ import (
 
 const (
 	ansiPat       = `\033\[(([0-9]+;?)*[a-zA-Z]?)`
-	rgbfgcolor    = "\033[38;5;%vm"
-	rgbbgcolor    = "\033[48;5;%vm"
-	truefgcolor   = "\033[38;2;%v;%v;%vm"
-	truebgcolor   = "\033[48;2;%v;%v;%vm"
+	rgbfgcolor    = "38;5;%v"
+	rgbbgcolor    = "48;5;%v"
+	truefgcolor   = "38;2;%v;%v;%v"
+	truebgcolor   = "48;2;%v;%v;%v"
 	clearLastLine = "\033[G\033[1A\033[K"
 )
 
 var spinGlyphs = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
 
+// NoColor disables all styling in templates, it is set when the NO_COLOR
+// environment variable is set. See https://no-color.org
+var NoColor = os.Getenv("NO_COLOR") != ""
+
 var funcMap = template.FuncMap{
 	"bright":    ansiStyler("3", "9"),
 	"Bright":    ansiStyler("4", "10"),
@@ -43,7 +48,15 @@ var funcMap = template.FuncMap{
 	"Magenta":   ansiStyler("45"),
 	"Cyan":      ansiStyler("46"),
 	"White":     ansiStyler("47"),
+	"color":     color,
 	"spin":      spin,
+	"syntax":    syntax,
+	"relpath":   relpath,
+	"pluralize": pluralize,
+	"truncate":  truncate,
+	"duration":  duration,
+	"pad":       pad,
+	"padLeft":   padLeft,
 }
 
 var spinIndex int
@@ -97,6 +110,9 @@ func (ansi ansiStr) String() string {
 
 func ansiStyler(attrs ...string) func(interface{}) string {
 	return func(v interface{}) string {
+		if NoColor {
+			return fmt.Sprintf("%v", v)
+		}
 		ansistr := parseAnsiString(fmt.Sprintf("%v", v))
 		if len(attrs) == 1 {
 			ansistr.add(attrs[0])
@@ -49,6 +49,13 @@ func TestAnsiStyler(t *testing.T) {
 	assert.Equal(t, "\033[31;4;39mHello World\033[m", actual)
 }
 
+func TestAnsiStylerNoColor(t *testing.T) {
+	NoColor = true
+	defer func() { NoColor = false }()
+	assert.Equal(t, "Hello World", ansiStyler("31")("Hello World"))
+	assert.Equal(t, "Hello World", ansiStyler("3", "9")("Hello World"))
+}
+
 func TestRemoveANSI(t *testing.T) {
 	str := "\033[31;4mHello \033[1mWorld\033[m"
 	out := removeANSI([]byte(str))
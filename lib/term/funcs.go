@@ -0,0 +1,91 @@
+package term
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// relpath makes a path relative to the working directory if it is inside of it
+func relpath(v interface{}) string {
+	path := fmt.Sprintf("%v", v)
+	wd, err := os.Getwd()
+	if err != nil || !filepath.IsAbs(path) {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// pluralize formats a count with a word, adding an s if the count is not one.
+// An irregular plural can be given as a second word.
+func pluralize(count int, words ...string) string {
+	if len(words) == 0 {
+		return fmt.Sprintf("%v", count)
+	} else if count == 1 {
+		return fmt.Sprintf("%v %v", count, words[0])
+	} else if len(words) > 1 {
+		return fmt.Sprintf("%v %v", count, words[1])
+	}
+	return fmt.Sprintf("%v %vs", count, words[0])
+}
+
+// truncate shortens a string to width characters, ending it with … if it was
+// cut. Colors are not counted towards the width but are lost if it is cut.
+func truncate(width int, v interface{}) string {
+	str := fmt.Sprintf("%v", v)
+	plain := string(removeANSI([]byte(str)))
+	if width <= 0 || utf8.RuneCountInString(plain) <= width {
+		return str
+	}
+	return string([]rune(plain)[:width-1]) + "…"
+}
+
+// duration formats a duration, or a number of seconds, rounded to a precision
+// that fits how long it is.
+func duration(v interface{}) string {
+	var d time.Duration
+	switch val := v.(type) {
+	case time.Duration:
+		d = val
+	case float64:
+		d = time.Duration(val * float64(time.Second))
+	case int:
+		d = time.Duration(val) * time.Second
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+	switch {
+	case d >= time.Minute:
+		return d.Round(time.Second).String()
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// pad fills a string with spaces on the right up to width characters, colors
+// are not counted towards the width.
+func pad(width int, v interface{}) string {
+	str := fmt.Sprintf("%v", v)
+	return str + strings.Repeat(" ", padding(width, str))
+}
+
+// padLeft fills a string with spaces on the left up to width characters
+func padLeft(width int, v interface{}) string {
+	str := fmt.Sprintf("%v", v)
+	return strings.Repeat(" ", padding(width, str)) + str
+}
+
+func padding(width int, str string) int {
+	if count := utf8.RuneCount(removeANSI([]byte(str))); count < width {
+		return width - count
+	}
+	return 0
+}
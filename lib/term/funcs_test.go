@@ -0,0 +1,48 @@
+package term
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelpath(t *testing.T) {
+	wd, _ := os.Getwd()
+	assert.Equal(t, filepath.Join("a", "b.go"), relpath(filepath.Join(wd, "a", "b.go")))
+	assert.Equal(t, "./b.go", relpath("./b.go"))
+	assert.Equal(t, "/not/in/wd.go", relpath("/not/in/wd.go"))
+}
+
+func TestPluralize(t *testing.T) {
+	assert.Equal(t, "1 test", pluralize(1, "test"))
+	assert.Equal(t, "2 tests", pluralize(2, "test"))
+	assert.Equal(t, "0 packages", pluralize(0, "package"))
+	assert.Equal(t, "3 matches", pluralize(3, "match", "matches"))
+	assert.Equal(t, "3", pluralize(3))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "TestLong…", truncate(9, "TestLongName"))
+	assert.Equal(t, "TestShort", truncate(9, "TestShort"))
+	assert.Equal(t, "\033[31mred\033[m", truncate(3, "\033[31mred\033[m"))
+	assert.Equal(t, "anything", truncate(0, "anything"))
+}
+
+func TestDuration(t *testing.T) {
+	assert.Equal(t, "1.23s", duration(1234*time.Millisecond))
+	assert.Equal(t, "350ms", duration(350400*time.Microsecond))
+	assert.Equal(t, "2m3s", duration(123456*time.Millisecond))
+	assert.Equal(t, "1.5s", duration(1.5))
+	assert.Equal(t, "2s", duration(2))
+	assert.Equal(t, "nope", duration("nope"))
+}
+
+func TestPad(t *testing.T) {
+	assert.Equal(t, "ab   ", pad(5, "ab"))
+	assert.Equal(t, "   ab", padLeft(5, "ab"))
+	assert.Equal(t, "abcdef", pad(5, "abcdef"))
+	assert.Equal(t, "\033[31mab\033[m ", pad(3, "\033[31mab\033[m"))
+}
@@ -0,0 +1,105 @@
+package term
+
+import (
+	"os"
+	"sync/atomic"
+
+	"golang.org/x/term"
+)
+
+// Key is a single key press, either one of the named keys below or the
+// character that was typed
+type Key string
+
+// Named keys that do not type a character
+const (
+	KeyUp    Key = "up"
+	KeyDown  Key = "down"
+	KeyLeft  Key = "left"
+	KeyRight Key = "right"
+	KeyEnter Key = "enter"
+	KeyEsc   Key = "esc"
+	KeyCtrlC Key = "ctrl-c"
+	KeyBack  Key = "backspace"
+)
+
+// rawOutput is set while a Keyboard has the terminal in raw mode
+var rawOutput int32
+
+// Keyboard reads key presses from a terminal that it keeps in raw mode until it
+// is closed. Since a raw terminal does not return to the start of the line on a
+// newline, ScreenBufs write \r\n while a Keyboard is open.
+type Keyboard struct {
+	Keys  chan Key
+	in    *os.File
+	state *term.State
+}
+
+// OpenKeyboard puts the terminal into raw mode and starts reading key presses
+// into Keys, which is closed if reading fails.
+func OpenKeyboard(in *os.File) (*Keyboard, error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	atomic.StoreInt32(&rawOutput, 1)
+	keyboard := &Keyboard{Keys: make(chan Key), in: in, state: state}
+	go keyboard.read()
+	return keyboard, nil
+}
+
+func (keyboard *Keyboard) read() {
+	defer close(keyboard.Keys)
+	buf := make([]byte, 16)
+	for {
+		n, err := keyboard.in.Read(buf)
+		if err != nil {
+			return
+		}
+		keyboard.Keys <- parseKey(buf[:n])
+	}
+}
+
+// Close puts the terminal back the way it was
+func (keyboard *Keyboard) Close() error {
+	atomic.StoreInt32(&rawOutput, 0)
+	return term.Restore(int(keyboard.in.Fd()), keyboard.state)
+}
+
+// ReadKey waits for a single key press. The terminal is only put into raw mode
+// while reading so that output written between reads is not affected.
+func ReadKey(in *os.File) (Key, error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(int(in.Fd()), state)
+	buf := make([]byte, 16)
+	n, err := in.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return parseKey(buf[:n]), nil
+}
+
+func parseKey(buf []byte) Key {
+	switch string(buf) {
+	case "\033[A", "\033OA":
+		return KeyUp
+	case "\033[B", "\033OB":
+		return KeyDown
+	case "\033[C", "\033OC":
+		return KeyRight
+	case "\033[D", "\033OD":
+		return KeyLeft
+	case "\r", "\n":
+		return KeyEnter
+	case "\033":
+		return KeyEsc
+	case "\003":
+		return KeyCtrlC
+	case "\177", "\b":
+		return KeyBack
+	}
+	return Key(buf)
+}
@@ -0,0 +1,17 @@
+package term
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKey(t *testing.T) {
+	assert.Equal(t, KeyUp, parseKey([]byte("\033[A")))
+	assert.Equal(t, KeyDown, parseKey([]byte("\033OB")))
+	assert.Equal(t, KeyEnter, parseKey([]byte("\r")))
+	assert.Equal(t, KeyEsc, parseKey([]byte("\033")))
+	assert.Equal(t, KeyCtrlC, parseKey([]byte{3}))
+	assert.Equal(t, KeyBack, parseKey([]byte("\177")))
+	assert.Equal(t, Key("q"), parseKey([]byte("q")))
+}
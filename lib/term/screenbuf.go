@@ -9,6 +9,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"text/template"
 
 	"golang.org/x/term"
@@ -30,21 +31,49 @@ func PrintlnTmpl(tmpl string, data interface{}, fs ...string) error {
 
 // ScreenBuf is a convenient way to write to terminal screens. It creates,
 // clears and, moves up or down lines as needed to write the output to the
-// terminal using ANSI escape codes.
+// terminal using ANSI escape codes. If the writer is not a terminal, like in
+// CI or when piped to a file, it will only ever append output.
 type ScreenBuf struct {
-	w    io.Writer
-	buf  *bytes.Buffer
-	mut  sync.Mutex
-	tmpl *template.Template
+	w           io.Writer
+	buf         *bytes.Buffer
+	mut         sync.Mutex
+	tmpl        *template.Template
+	interactive bool
 }
 
-// NewScreenBuf creates and initializes a new ScreenBuf.
+// NewScreenBuf creates and initializes a new ScreenBuf. It will panic if any
+// of the template sources cannot be parsed.
 func NewScreenBuf(w io.Writer, sources ...string) *ScreenBuf {
+	screen, err := ParseScreenBuf(w, sources...)
+	if err != nil {
+		panic(err)
+	}
+	return screen
+}
+
+// ParseScreenBuf creates a new ScreenBuf, returning an error if any of the
+// template sources cannot be parsed. Templates defined in later sources replace
+// those with the same name in earlier ones.
+func ParseScreenBuf(w io.Writer, sources ...string) (*ScreenBuf, error) {
 	tmpl := template.New("screenbuf").Funcs(funcMap)
 	for _, src := range sources {
-		template.Must(tmpl.Parse(src))
+		if _, err := tmpl.Parse(src); err != nil {
+			return nil, err
+		}
 	}
-	return &ScreenBuf{buf: &bytes.Buffer{}, w: w, tmpl: tmpl}
+	return &ScreenBuf{buf: &bytes.Buffer{}, w: w, tmpl: tmpl, interactive: IsTerminal(w)}, nil
+}
+
+// IsTerminal checks if the writer is an interactive terminal
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// Interactive is true if the screen can be redrawn. If it is false all renders
+// will be appended to the output.
+func (s *ScreenBuf) Interactive() bool {
+	return s.interactive
 }
 
 // Render will write a text/template out to the console, using a mutex so that
@@ -80,6 +109,9 @@ func (s *ScreenBuf) Reset() error {
 	defer s.mut.Unlock()
 	linecount := bytes.Count(s.buf.Bytes(), []byte("\n"))
 	s.buf.Reset()
+	if !s.interactive {
+		return nil
+	}
 	_, err := s.buf.Write([]byte(strings.Repeat(clearLastLine, linecount)))
 	return err
 }
@@ -108,15 +140,18 @@ func (s *ScreenBuf) Write(in string, data interface{}) error {
 func (s *ScreenBuf) write(in string, data interface{}) error {
 	s.mut.Lock()
 	defer s.mut.Unlock()
-	width, _, err := term.GetSize(int(os.Stdin.Fd()))
-	if err != nil {
-		width = defaultTermWidth
+	tmpl := in
+	if s.interactive {
+		width, _, err := term.GetSize(int(os.Stdin.Fd()))
+		if err != nil || width <= 0 {
+			width = defaultTermWidth
+		}
+		tmpl = wrapANSI(in, width)
 	}
-	tmpl := wrapANSI(in, width)
 	if !strings.HasSuffix(tmpl, "\n") {
 		tmpl += "\n"
 	}
-	_, err = s.buf.WriteString(tmpl)
+	_, err := s.buf.WriteString(tmpl)
 	return err
 }
 
@@ -125,6 +160,10 @@ func (s *ScreenBuf) write(in string, data interface{}) error {
 func (s *ScreenBuf) Flush() error {
 	s.mut.Lock()
 	defer s.mut.Unlock()
-	_, err := io.Copy(s.w, bytes.NewBuffer(s.buf.Bytes()))
+	out := s.buf.Bytes()
+	if atomic.LoadInt32(&rawOutput) == 1 {
+		out = bytes.ReplaceAll(out, []byte("\n"), []byte("\r\n"))
+	}
+	_, err := io.Copy(s.w, bytes.NewBuffer(out))
 	return err
 }
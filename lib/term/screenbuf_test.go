@@ -18,9 +18,21 @@ func TestScreenBufRender(t *testing.T) {
 	assert.Equal(t, "\x1b[32mhello\x1b[m\n\x1b[1mworld\x1b[m\n", buf.String())
 }
 
+func TestParseScreenBuf(t *testing.T) {
+	var buf bytes.Buffer
+	screen, err := ParseScreenBuf(&buf, `{{define "a"}}one{{end}}`, `{{define "a"}}two{{end}}`)
+	assert.Nil(t, err)
+	assert.Nil(t, screen.RenderTmpl("a", nil))
+	assert.Equal(t, "two\n", buf.String())
+
+	_, err = ParseScreenBuf(&buf, `{{define "a"}}`)
+	assert.NotNil(t, err)
+}
+
 func TestScreenBufReset(t *testing.T) {
 	var buf bytes.Buffer
 	screen := NewScreenBuf(&buf)
+	screen.interactive = true
 	screen.buf.WriteString(`This is
 A Buffer full of
 Lines that need to
@@ -30,6 +42,19 @@ Be Reset`)
 	assert.Equal(t, strings.Repeat(clearLastLine, 3), screen.buf.String())
 }
 
+func TestScreenBufResetNonInteractive(t *testing.T) {
+	var buf bytes.Buffer
+	screen := NewScreenBuf(&buf)
+	assert.False(t, screen.Interactive())
+	screen.buf.WriteString("This is\nA Buffer\n")
+	err := screen.Reset()
+	assert.Nil(t, err)
+	assert.Equal(t, "", screen.buf.String())
+	assert.Nil(t, screen.Render("{{.}}", "first"))
+	assert.Nil(t, screen.Render("{{.}}", "second"))
+	assert.Equal(t, "first\nsecond\n", buf.String())
+}
+
 func TestScreenBufWrite(t *testing.T) {
 	var buf bytes.Buffer
 	screen := NewScreenBuf(&buf)
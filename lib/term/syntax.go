@@ -0,0 +1,66 @@
+package term
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+var syntaxStyles = map[string]func(interface{}) string{
+	"keyword": ansiStyler("35"),
+	"string":  ansiStyler("32"),
+	"number":  ansiStyler("36"),
+	"comment": ansiStyler("2"),
+	"const":   ansiStyler("36"),
+}
+
+// syntax will colorize a fragment of go code. It does not need to be a complete
+// source file, any tokens that cannot be scanned are left as is.
+func syntax(v interface{}) string {
+	src := []byte(fmt.Sprintf("%v", v))
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, func(token.Position, string) {}, scanner.ScanComments)
+	var out strings.Builder
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		} else if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		offset := file.Offset(pos)
+		text := lit
+		if text == "" {
+			text = tok.String()
+		}
+		end := offset + len(text)
+		if offset < last || end > len(src) {
+			continue
+		}
+		out.Write(src[last:offset])
+		out.WriteString(styleToken(tok, text))
+		last = end
+	}
+	out.Write(src[last:])
+	return out.String()
+}
+
+func styleToken(tok token.Token, text string) string {
+	switch {
+	case tok.IsKeyword():
+		return syntaxStyles["keyword"](text)
+	case tok == token.STRING || tok == token.CHAR:
+		return syntaxStyles["string"](text)
+	case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+		return syntaxStyles["number"](text)
+	case tok == token.COMMENT:
+		return syntaxStyles["comment"](text)
+	case tok == token.IDENT && (text == "true" || text == "false" || text == "nil"):
+		return syntaxStyles["const"](text)
+	}
+	return text
+}
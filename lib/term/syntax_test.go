@@ -0,0 +1,17 @@
+package term
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyntax(t *testing.T) {
+	assert.Equal(t, "", syntax(""))
+	assert.Equal(t, "  a + b", syntax("  a + b"))
+	assert.Equal(t, "\x1b[35mreturn\x1b[m a - b", syntax("return a - b"))
+	assert.Equal(t, "x := \x1b[32m\"str\"\x1b[m \x1b[2m// note\x1b[m", syntax(`x := "str" // note`))
+	assert.Equal(t, "\x1b[35mif\x1b[m err != \x1b[36mnil\x1b[m {", syntax("if err != nil {"))
+	assert.Equal(t, "n := \x1b[36m42\x1b[m", syntax("n := 42"))
+	assert.Equal(t, `"unterminated`, string(removeANSI([]byte(syntax(`"unterminated`)))))
+}
@@ -0,0 +1,256 @@
+package term
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ColorDepth is how many colors a terminal can show
+type ColorDepth int
+
+const (
+	// BasicColor is the 8 ANSI colors and their bright versions
+	BasicColor ColorDepth = iota
+	// Color256 is the xterm 256 color palette
+	Color256
+	// TrueColor is 24 bit rgb color
+	TrueColor
+)
+
+// Theme maps the semantic names used in templates, like pass or diff-add, to a
+// style. A style is a space separated list of attributes (bold, faint, italic,
+// underline, invert) and colors. Colors can be one of the basic color names
+// like red or bright-red, a 256 color number, or a #rrggbb hex color. A color
+// after "on" is used for the background.
+type Theme map[string]string
+
+// Depth is the color depth of the terminal, colors in a theme are converted down
+// to the closest color that the terminal can show.
+var Depth = DetectColorDepth(os.Getenv("COLORTERM"), os.Getenv("TERM"))
+
+// Themes are the themes that ship with og
+var Themes = map[string]Theme{
+	"default": {
+		"pass":      "green",
+		"fail":      "red",
+		"skip":      "yellow",
+		"path":      "cyan",
+		"line":      "bold",
+		"diff-add":  "green",
+		"diff-del":  "red",
+		"warn":      "yellow",
+		"error":     "magenta",
+		"highlight": "bold on red",
+	},
+	// colorblind uses the Okabe-Ito palette which avoids telling results apart
+	// by red and green
+	"colorblind": {
+		"pass":      "#0072b2",
+		"fail":      "#e69f00 bold",
+		"skip":      "#f0e442",
+		"path":      "#56b4e9",
+		"line":      "bold",
+		"diff-add":  "#0072b2",
+		"diff-del":  "#e69f00",
+		"warn":      "#f0e442",
+		"error":     "#cc79a7",
+		"highlight": "bold on #e69f00",
+	},
+}
+
+var (
+	theme      = Themes["default"]
+	basicNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+	attributes = map[string]string{"bold": "1", "faint": "2", "italic": "3", "underline": "4", "invert": "7"}
+	// basicRGB is how xterm draws the 16 basic colors, used to find the closest
+	basicRGB = [16][3]int{
+		{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+		{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+	}
+	cubeLevels = []int{0, 95, 135, 175, 215, 255}
+)
+
+// DetectColorDepth works out the color depth from the COLORTERM and TERM
+// environment variables
+func DetectColorDepth(colorterm, termName string) ColorDepth {
+	if colorterm == "truecolor" || colorterm == "24bit" {
+		return TrueColor
+	} else if strings.Contains(termName, "256color") {
+		return Color256
+	}
+	return BasicColor
+}
+
+// SetTheme changes the theme used by the color template func. Any names that
+// the theme does not set are taken from the default theme.
+func SetTheme(custom Theme) error {
+	merged := Theme{}
+	for name, style := range Themes["default"] {
+		merged[name] = style
+	}
+	for name, style := range custom {
+		if _, err := parseStyle(style, Depth); err != nil {
+			return fmt.Errorf("invalid style for %v: %v", name, err)
+		}
+		merged[name] = style
+	}
+	theme = merged
+	return nil
+}
+
+// color styles a value with the style that the theme has for name
+func color(name string, v interface{}) (string, error) {
+	style, ok := theme[name]
+	if !ok {
+		return "", fmt.Errorf("theme has no color named %v", name)
+	}
+	codes, err := parseStyle(style, Depth)
+	if err != nil {
+		return "", err
+	}
+	if NoColor {
+		return fmt.Sprintf("%v", v), nil
+	}
+	ansistr := parseAnsiString(fmt.Sprintf("%v", v))
+	for _, code := range codes {
+		ansistr.add(code)
+	}
+	return ansistr.String(), nil
+}
+
+func parseStyle(style string, depth ColorDepth) ([]string, error) {
+	codes := []string{}
+	background := false
+	for _, token := range strings.Fields(style) {
+		if token == "on" {
+			background = true
+			continue
+		} else if code, ok := attributes[token]; ok {
+			codes = append(codes, code)
+			continue
+		}
+		code, err := colorCode(token, background, depth)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		background = false
+	}
+	return codes, nil
+}
+
+// colorCode converts a single color to the ansi code for it at the color depth
+func colorCode(token string, background bool, depth ColorDepth) (string, error) {
+	name := strings.TrimPrefix(token, "bright-")
+	for i, basic := range basicNames {
+		if name == basic {
+			return basicCode(i+boolToInt(name != token)*8, background), nil
+		}
+	}
+	if strings.HasPrefix(token, "#") {
+		rgb, err := strconv.ParseUint(strings.TrimPrefix(token, "#"), 16, 32)
+		if err != nil || len(token) != 7 {
+			return "", fmt.Errorf("invalid hex color %v", token)
+		}
+		r, g, b := int(rgb>>16), int(rgb>>8&0xff), int(rgb&0xff)
+		switch depth {
+		case TrueColor:
+			format := truefgcolor
+			if background {
+				format = truebgcolor
+			}
+			return fmt.Sprintf(format, r, g, b), nil
+		case Color256:
+			return color256Code(rgbTo256(r, g, b), background), nil
+		}
+		return basicCode(closestBasic(r, g, b), background), nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > 255 {
+		return "", fmt.Errorf("unknown color %v", token)
+	} else if depth == BasicColor {
+		r, g, b := color256ToRGB(index)
+		return basicCode(closestBasic(r, g, b), background), nil
+	}
+	return color256Code(index, background), nil
+}
+
+func basicCode(index int, background bool) string {
+	code := 30 + index
+	if index >= 8 {
+		code = 90 + index - 8
+	}
+	if background {
+		code += 10
+	}
+	return strconv.Itoa(code)
+}
+
+func color256Code(index int, background bool) string {
+	if background {
+		return fmt.Sprintf(rgbbgcolor, index)
+	}
+	return fmt.Sprintf(rgbfgcolor, index)
+}
+
+// rgbTo256 finds the closest color in the 256 color cube or grayscale ramp
+func rgbTo256(r, g, b int) int {
+	if r == g && g == b {
+		if r < 8 {
+			return 16
+		} else if r > 248 {
+			return 231
+		}
+		return 232 + (r-8)*24/247
+	}
+	return 16 + 36*cubeIndex(r) + 6*cubeIndex(g) + cubeIndex(b)
+}
+
+func cubeIndex(val int) int {
+	closest := 0
+	for i, level := range cubeLevels {
+		if abs(level-val) < abs(cubeLevels[closest]-val) {
+			closest = i
+		}
+	}
+	return closest
+}
+
+func color256ToRGB(index int) (int, int, int) {
+	switch {
+	case index < 16:
+		return basicRGB[index][0], basicRGB[index][1], basicRGB[index][2]
+	case index < 232:
+		index -= 16
+		return cubeLevels[index/36], cubeLevels[index/6%6], cubeLevels[index%6]
+	}
+	gray := 8 + (index-232)*10
+	return gray, gray, gray
+}
+
+func closestBasic(r, g, b int) int {
+	closest, best := 0, -1
+	for i, rgb := range basicRGB {
+		dist := (rgb[0]-r)*(rgb[0]-r) + (rgb[1]-g)*(rgb[1]-g) + (rgb[2]-b)*(rgb[2]-b)
+		if best < 0 || dist < best {
+			closest, best = i, dist
+		}
+	}
+	return closest
+}
+
+func abs(val int) int {
+	if val < 0 {
+		return -val
+	}
+	return val
+}
+
+func boolToInt(val bool) int {
+	if val {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package term
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectColorDepth(t *testing.T) {
+	assert.Equal(t, TrueColor, DetectColorDepth("truecolor", "xterm"))
+	assert.Equal(t, TrueColor, DetectColorDepth("24bit", ""))
+	assert.Equal(t, Color256, DetectColorDepth("", "xterm-256color"))
+	assert.Equal(t, BasicColor, DetectColorDepth("", "xterm"))
+}
+
+func TestParseStyle(t *testing.T) {
+	cases := []struct {
+		style string
+		depth ColorDepth
+		codes []string
+	}{
+		{"red", TrueColor, []string{"31"}},
+		{"bright-red on blue", BasicColor, []string{"91", "44"}},
+		{"bold underline", BasicColor, []string{"1", "4"}},
+		{"#0072b2", TrueColor, []string{"38;2;0;114;178"}},
+		{"#0072b2", Color256, []string{"38;5;25"}},
+		{"#0072b2", BasicColor, []string{"36"}},
+		{"on #808080", Color256, []string{"48;5;243"}},
+		{"208", Color256, []string{"38;5;208"}},
+		{"208", BasicColor, []string{"33"}},
+	}
+	for _, c := range cases {
+		codes, err := parseStyle(c.style, c.depth)
+		assert.Nil(t, err, c.style)
+		assert.Equal(t, c.codes, codes, c.style)
+	}
+
+	_, err := parseStyle("purple", BasicColor)
+	assert.NotNil(t, err)
+	_, err = parseStyle("#12345", TrueColor)
+	assert.NotNil(t, err)
+	_, err = parseStyle("256", Color256)
+	assert.NotNil(t, err)
+}
+
+func TestColor(t *testing.T) {
+	defer SetTheme(nil)
+	str, err := color("pass", "ok")
+	assert.Nil(t, err)
+	assert.Equal(t, "\033[32mok\033[m", str)
+
+	assert.Nil(t, SetTheme(Theme{"pass": "blue bold"}))
+	str, _ = color("pass", "ok")
+	assert.Equal(t, "\033[34;1mok\033[m", str)
+	str, _ = color("fail", "no")
+	assert.Equal(t, "\033[31mno\033[m", str)
+	str, _ = color("highlight", "no")
+	assert.Equal(t, "\033[1;41mno\033[m", str)
+
+	_, err = color("nope", "no")
+	assert.NotNil(t, err)
+	assert.NotNil(t, SetTheme(Theme{"pass": "purple"}))
+}
+
+func TestThemes(t *testing.T) {
+	for name, theme := range Themes {
+		assert.Equal(t, len(Themes["default"]), len(theme), name)
+		for key, style := range theme {
+			_, err := parseStyle(style, TrueColor)
+			assert.Nil(t, err, name+" "+key)
+		}
+	}
+}
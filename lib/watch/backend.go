@@ -0,0 +1,223 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// pollInterval is how often the poller looks for changes when it is used
+// because file system events are not available.
+const pollInterval = 500 * time.Millisecond
+
+type (
+	// Backend notifies a Watcher about changes to the files in the directories
+	// that were added to it. Events are not sent for the contents of sub
+	// directories, those have to be added on their own.
+	Backend interface {
+		Add(dir string) error
+		Remove(dir string) error
+		Events() <-chan fsnotify.Event
+		Errors() <-chan error
+		Close() error
+	}
+	// notifyBackend uses the file system events of the os
+	notifyBackend struct {
+		*fsnotify.Watcher
+	}
+	// poller finds changes by reading the watched directories on an interval,
+	// which works on network and container mounts that do not send events.
+	poller struct {
+		interval time.Duration
+		mut      sync.Mutex
+		dirs     map[string]map[string]fileState
+		events   chan fsnotify.Event
+		errors   chan error
+		done     chan struct{}
+		close    sync.Once
+	}
+	// fileState is what the poller knows about a file from the last poll
+	fileState struct {
+		dir     bool
+		size    int64
+		modTime time.Time
+		sum     string
+	}
+)
+
+// NewNotify creates a backend that uses file system events
+func NewNotify() (Backend, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return notifyBackend{Watcher: fs}, nil
+}
+
+func (backend notifyBackend) Events() <-chan fsnotify.Event { return backend.Watcher.Events }
+func (backend notifyBackend) Errors() <-chan error          { return backend.Watcher.Errors }
+
+// NewPoller creates a backend that compares the modification time, size and
+// checksum of files every interval.
+func NewPoller(interval time.Duration) Backend {
+	backend := &poller{
+		interval: interval,
+		dirs:     map[string]map[string]fileState{},
+		events:   make(chan fsnotify.Event),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+	}
+	go backend.run()
+	return backend
+}
+
+func (backend *poller) Add(dir string) error {
+	files, err := readState(dir)
+	if err != nil {
+		return err
+	}
+	backend.mut.Lock()
+	defer backend.mut.Unlock()
+	backend.dirs[dir] = files
+	return nil
+}
+
+func (backend *poller) Remove(dir string) error {
+	backend.mut.Lock()
+	defer backend.mut.Unlock()
+	delete(backend.dirs, dir)
+	return nil
+}
+
+func (backend *poller) Events() <-chan fsnotify.Event { return backend.events }
+func (backend *poller) Errors() <-chan error          { return backend.errors }
+
+func (backend *poller) Close() error {
+	backend.close.Do(func() { close(backend.done) })
+	return nil
+}
+
+func (backend *poller) run() {
+	defer close(backend.events)
+	defer close(backend.errors)
+	ticker := time.NewTicker(backend.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-backend.done:
+			return
+		case <-ticker.C:
+			if !backend.poll() {
+				return
+			}
+		}
+	}
+}
+
+// poll compares every watched directory to its last state, returning false if
+// the poller was closed while sending events.
+func (backend *poller) poll() bool {
+	backend.mut.Lock()
+	dirs := make([]string, 0, len(backend.dirs))
+	for dir := range backend.dirs {
+		dirs = append(dirs, dir)
+	}
+	backend.mut.Unlock()
+
+	for _, dir := range dirs {
+		backend.mut.Lock()
+		last, ok := backend.dirs[dir]
+		backend.mut.Unlock()
+		if !ok {
+			continue
+		}
+		current, err := readState(dir)
+		if os.IsNotExist(err) {
+			// the parent directory reports the removal
+			continue
+		} else if err != nil {
+			if !backend.send(nil, err) {
+				return false
+			}
+			continue
+		}
+		events := diffState(dir, last, current)
+		backend.mut.Lock()
+		if _, ok := backend.dirs[dir]; ok {
+			backend.dirs[dir] = current
+		}
+		backend.mut.Unlock()
+		for _, event := range events {
+			if !backend.send(&event, nil) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (backend *poller) send(event *fsnotify.Event, err error) bool {
+	if event != nil {
+		select {
+		case backend.events <- *event:
+		case <-backend.done:
+			return false
+		}
+	} else {
+		select {
+		case backend.errors <- err:
+		case <-backend.done:
+			return false
+		}
+	}
+	return true
+}
+
+// readState reads the files in dir
+func readState(dir string) (map[string]fileState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]fileState{}
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil {
+			files[entry.Name()] = fileState{dir: info.IsDir(), size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	return files, nil
+}
+
+// diffState makes the events for the differences between two states of a dir
+func diffState(dir string, last, current map[string]fileState) []fsnotify.Event {
+	events := []fsnotify.Event{}
+	for name, state := range current {
+		path := filepath.Join(dir, name)
+		if prev, ok := last[name]; !ok || prev.dir != state.dir {
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+		} else if !state.dir && modified(path, prev, &state) {
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+		}
+		current[name] = state
+	}
+	for name := range last {
+		if _, ok := current[name]; !ok {
+			events = append(events, fsnotify.Event{Name: filepath.Join(dir, name), Op: fsnotify.Remove})
+		}
+	}
+	return events
+}
+
+// modified checks if a file changed since the last poll. Files that kept their
+// size but were touched are only reported if their checksum changed.
+func modified(path string, prev fileState, state *fileState) bool {
+	if prev.size == state.size && prev.modTime.Equal(state.modTime) {
+		state.sum = prev.sum
+		return false
+	}
+	state.sum, _ = sum(path)
+	return prev.size != state.size || prev.sum == "" || prev.sum != state.sum
+}
@@ -0,0 +1,65 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoller(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	assert.Nil(t, os.WriteFile(path, []byte("package a\n"), 0644))
+
+	backend := NewPoller(10 * time.Millisecond)
+	defer backend.Close()
+	assert.Nil(t, backend.Add(dir))
+
+	next := func() fsnotify.Event {
+		select {
+		case event := <-backend.Events():
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event from the poller")
+		}
+		return fsnotify.Event{}
+	}
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "b.go"), []byte("package a\n"), 0644))
+	assert.Equal(t, fsnotify.Event{Name: filepath.Join(dir, "b.go"), Op: fsnotify.Create}, next())
+
+	later := time.Now().Add(time.Minute)
+	assert.Nil(t, os.WriteFile(path, []byte("package b\n"), 0644))
+	assert.Nil(t, os.Chtimes(path, later, later))
+	assert.Equal(t, fsnotify.Event{Name: path, Op: fsnotify.Write}, next())
+
+	// touching a file without changing it is not a change
+	later = later.Add(time.Minute)
+	assert.Nil(t, os.Chtimes(path, later, later))
+	assert.Nil(t, os.Remove(filepath.Join(dir, "b.go")))
+	assert.Equal(t, fsnotify.Event{Name: filepath.Join(dir, "b.go"), Op: fsnotify.Remove}, next())
+}
+
+func TestDiffState(t *testing.T) {
+	now := time.Now()
+	last := map[string]fileState{
+		"same.go":    {size: 1, modTime: now, sum: "x"},
+		"removed.go": {size: 1, modTime: now},
+		"sub":        {dir: true, modTime: now},
+	}
+	current := map[string]fileState{
+		"same.go": {size: 1, modTime: now},
+		"new.go":  {size: 1, modTime: now},
+		"sub":     {dir: true, modTime: now.Add(time.Second)},
+	}
+	events := diffState("dir", last, current)
+	assert.ElementsMatch(t, []fsnotify.Event{
+		{Name: filepath.Join("dir", "new.go"), Op: fsnotify.Create},
+		{Name: filepath.Join("dir", "removed.go"), Op: fsnotify.Remove},
+	}, events)
+	assert.Equal(t, "x", current["same.go"].sum)
+}
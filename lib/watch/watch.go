@@ -1,84 +1,259 @@
 package watch
 
 import (
+	"bufio"
 	"crypto/md5"
 	"fmt"
 	"io"
 	"os"
-	"os/signal"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"syscall"
+	"sync"
 
 	"github.com/fsnotify/fsnotify"
+
+	"github.com/tanema/og/lib/glob"
 )
 
+// Everything is sent as a change when go.mod or go.sum change since that can
+// change every package.
+const Everything = "./..."
+
+// Watcher watches the directories of a tree for changes to go files and the
+// files that packages use, like testdata and embedded files. Only directories
+// are watched, which keeps the number of watches low on big trees, and
+// directories are added and removed as they are created and deleted. If file
+// system events stop working, like when the inotify watch limit is reached,
+// the Watcher switches to polling and sends the reason on Fallback.
 type Watcher struct {
-	fs        *fsnotify.Watcher
-	Changes   chan string
-	Errors    chan error
-	signals   chan os.Signal
-	checksums map[string]string
+	backend    Backend
+	mut        sync.Mutex
+	closed     bool
+	Polling    bool
+	Changes    chan string
+	Errors     chan error
+	Fallback   chan error
+	checksums  map[string]string
+	dirs       map[string]bool
+	ignore     []*glob.Pattern
+	gitignores map[string][]gitignore
+	embeds     map[string][]string
 }
 
-func New() (*Watcher, error) {
-	watcher := &Watcher{
-		Changes:   make(chan string, 1),
-		Errors:    make(chan error, 1),
-		signals:   make(chan os.Signal, 1),
-		checksums: map[string]string{},
-	}
-	var err error
-	go watcher.watchSignals()
-	watcher.fs, err = fsnotify.NewWatcher()
+// gitignore is a single pattern from a .gitignore file
+type gitignore struct {
+	pattern *glob.Pattern
+	negate  bool
+	dirOnly bool
+}
+
+// New watches every directory under the working directory except hidden ones,
+// vendor, node_modules and those matched by the ignore globs or a .gitignore
+// file. File system events are used unless poll is set, falling back to
+// polling if they cannot be watched.
+func New(ignore []string, poll bool) (*Watcher, error) {
+	if !poll {
+		if backend, err := NewNotify(); err == nil {
+			return NewWithBackend(backend, ignore)
+		}
+	}
+	return NewWithBackend(NewPoller(pollInterval), ignore)
+}
+
+// NewWithBackend watches the working directory like New but with the given
+// backend, which is closed if watching fails. Backends other than the poller
+// are replaced by one if they fail to add a directory.
+func NewWithBackend(backend Backend, ignore []string) (*Watcher, error) {
+	patterns, err := glob.CompileAll(ignore)
 	if err != nil {
+		backend.Close()
 		return nil, err
 	}
-	return watcher, filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		} else if info.IsDir() || strings.HasSuffix(path, ".go") {
-			if err := watcher.fs.Add(path); err != nil {
-				return err
-			}
-		}
-		return nil
-	})
+	_, polling := backend.(*poller)
+	watcher := &Watcher{
+		backend:    backend,
+		Polling:    polling,
+		Changes:    make(chan string, 1),
+		Errors:     make(chan error, 1),
+		Fallback:   make(chan error, 1),
+		checksums:  map[string]string{},
+		dirs:       map[string]bool{},
+		ignore:     patterns,
+		gitignores: map[string][]gitignore{},
+		embeds:     map[string][]string{},
+	}
+	if err := watcher.addTree(".", nil); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return watcher, nil
 }
 
+// Start sends changes until the watcher is closed
 func (watcher *Watcher) Start() error {
 	defer close(watcher.Changes)
 	defer close(watcher.Errors)
 	for {
+		backend := watcher.current()
 		select {
-		case event, ok := <-watcher.fs.Events:
+		case event, ok := <-backend.Events():
 			if !ok {
 				return nil
 			}
 			watcher.onEvent(event)
-		case err := <-watcher.fs.Errors:
-			watcher.Errors <- err
+		case err, ok := <-backend.Errors():
+			if !ok {
+				return nil
+			} else if err := watcher.fallback(err); err != nil {
+				watcher.Errors <- err
+			}
 		}
 	}
 }
 
-func (watcher *Watcher) onEvent(event fsnotify.Event) {
-	if event.Op&fsnotify.Write != fsnotify.Write || !strings.HasSuffix(event.Name, ".go") {
-		return
+// Close stops watching
+func (watcher *Watcher) Close() error {
+	watcher.mut.Lock()
+	defer watcher.mut.Unlock()
+	watcher.closed = true
+	return watcher.backend.Close()
+}
+
+func (watcher *Watcher) current() Backend {
+	watcher.mut.Lock()
+	defer watcher.mut.Unlock()
+	return watcher.backend
+}
+
+// fallback switches to polling after file system events failed with cause and
+// watches every directory again. The cause is returned if the watcher was
+// already polling or is closed.
+func (watcher *Watcher) fallback(cause error) error {
+	watcher.mut.Lock()
+	defer watcher.mut.Unlock()
+	if watcher.Polling || watcher.closed {
+		return cause
+	}
+	watcher.backend.Close()
+	watcher.backend = NewPoller(pollInterval)
+	watcher.Polling = true
+	for dir := range watcher.dirs {
+		if err := watcher.backend.Add(dir); os.IsNotExist(err) {
+			delete(watcher.dirs, dir)
+		} else if err != nil {
+			return fmt.Errorf("cannot watch %v: %v", dir, err)
+		}
 	}
-	path := event.Name
-	if strings.HasSuffix(path, ".go") {
-		checksum, err := sum(path)
+	select {
+	case watcher.Fallback <- cause:
+	default:
+	}
+	return nil
+}
+
+// addTree watches dir and the directories in it. The files that are found are
+// passed to found so that the files of a new directory count as changes.
+func (watcher *Watcher) addTree(dir string, found func(string)) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			watcher.Errors <- err
-		} else if checksum == watcher.checksums[path] {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		} else if !info.IsDir() {
+			if found != nil && !watcher.ignored(path, false) {
+				found(path)
+			}
+			return nil
+		} else if path != dir && watcher.ignored(path, true) {
+			return filepath.SkipDir
+		}
+		watcher.loadGitignore(path)
+		if err := watcher.add(path); err != nil {
+			return fmt.Errorf("cannot watch %v: %v", path, err)
+		}
+		watcher.dirs[path] = true
+		return nil
+	})
+}
+
+// add watches a single directory, switching to polling if the backend cannot
+func (watcher *Watcher) add(dir string) error {
+	if err := watcher.current().Add(dir); err == nil {
+		return nil
+	} else if err := watcher.fallback(err); err != nil {
+		return err
+	}
+	return watcher.current().Add(dir)
+}
+
+// removeTree stops watching dir and the directories in it after it was deleted
+// or moved away.
+func (watcher *Watcher) removeTree(dir string) {
+	for path := range watcher.dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			watcher.current().Remove(path)
+			delete(watcher.dirs, path)
+			delete(watcher.gitignores, path)
+			delete(watcher.embeds, path)
+		}
+	}
+}
+
+func (watcher *Watcher) onEvent(event fsnotify.Event) {
+	path := filepath.Clean(event.Name)
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 && watcher.dirs[path] {
+		watcher.removeTree(path)
+		return
+	} else if event.Op&fsnotify.Create == fsnotify.Create {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			if !watcher.ignored(path, true) {
+				if err := watcher.addTree(path, watcher.onChange); err != nil {
+					watcher.Errors <- err
+				}
+			}
 			return
-		} else {
-			watcher.checksums[path] = checksum
 		}
 	}
-	if strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go") {
-		path = strings.ReplaceAll(path, ".go", "_test.go")
+	if filepath.Base(path) == ".gitignore" {
+		watcher.loadGitignore(filepath.Dir(path))
+		return
+	} else if watcher.ignored(path, false) {
+		return
+	} else if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		delete(watcher.checksums, path)
+		watcher.onChange(path)
+		return
+	}
+	checksum, err := sum(path)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		watcher.Errors <- err
+		return
+	} else if checksum == watcher.checksums[path] {
+		return
+	}
+	watcher.checksums[path] = checksum
+	watcher.onChange(path)
+}
+
+// onChange sends the test file for a changed go file, or its directory if it
+// does not have one. Other files, and go files in fixture directories, send the
+// package that uses them.
+func (watcher *Watcher) onChange(path string) {
+	if !strings.HasSuffix(path, ".go") || inFixtures(path) {
+		if pkg, ok := watcher.owner(path); ok {
+			watcher.Changes <- pkg
+		}
+		return
+	}
+	// the embed patterns are read again in case the directives changed
+	delete(watcher.embeds, filepath.Dir(path))
+	if !strings.HasSuffix(path, "_test.go") {
+		path = strings.TrimSuffix(path, ".go") + "_test.go"
 	}
 	if _, err := os.Stat(path); err != nil {
 		path = filepath.Dir(path)
@@ -86,14 +261,160 @@ func (watcher *Watcher) onEvent(event fsnotify.Event) {
 	watcher.Changes <- path
 }
 
-func (watcher *Watcher) watchSignals() {
-	signal.Notify(watcher.signals, syscall.SIGINFO)
-	go func() {
-		for {
-			<-watcher.signals
-			watcher.Changes <- "./..."
+// ignored checks if a path should not be watched
+func (watcher *Watcher) ignored(path string, dir bool) bool {
+	if dir {
+		if name := filepath.Base(path); strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" {
+			return true
+		}
+	}
+	if glob.Any(watcher.ignore, path) {
+		return true
+	}
+	for parent := filepath.Dir(path); ; parent = filepath.Dir(parent) {
+		rel, err := filepath.Rel(parent, path)
+		if err != nil {
+			break
+		}
+		// patterns are checked from the deepest .gitignore up, the last
+		// matching pattern in a file decides and deeper files take precedence
+		for i := len(watcher.gitignores[parent]) - 1; i >= 0; i-- {
+			rule := watcher.gitignores[parent][i]
+			if (!rule.dirOnly || dir) && rule.pattern.Match(rel) {
+				return !rule.negate
+			}
+		}
+		if parent == "." || parent == string(filepath.Separator) {
+			break
+		}
+	}
+	return false
+}
+
+// owner finds the package directory that a file that is not package source
+// belongs to, either because it is in the testdata or another fixture directory
+// of the package or matches one of its go:embed patterns.
+func (watcher *Watcher) owner(file string) (string, bool) {
+	if name := filepath.Base(file); name == "go.mod" || name == "go.sum" {
+		return Everything, true
+	}
+	parts := strings.Split(filepath.ToSlash(file), "/")
+	for i, part := range parts[:len(parts)-1] {
+		if fixtureDir(part) {
+			return filepath.Clean(filepath.FromSlash(strings.Join(parts[:i], "/"))), true
+		}
+	}
+	for dir := filepath.Dir(file); ; dir = filepath.Dir(dir) {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			break
+		}
+		for _, pattern := range watcher.embedPatterns(dir) {
+			if embedMatch(pattern, filepath.ToSlash(rel)) {
+				return dir, true
+			}
+		}
+		if dir == "." || dir == string(filepath.Separator) {
+			break
+		}
+	}
+	return "", false
+}
+
+// inFixtures checks if a file is in a fixture directory, its go files are not
+// a package of their own.
+func inFixtures(file string) bool {
+	parts := strings.Split(filepath.ToSlash(file), "/")
+	for _, part := range parts[:len(parts)-1] {
+		if fixtureDir(part) {
+			return true
 		}
-	}()
+	}
+	return false
+}
+
+// fixtureDir checks if a directory is one that go leaves out of packages and
+// that tests keep their files in, like testdata or _fixtures.
+func fixtureDir(name string) bool {
+	return name == "testdata" || strings.HasPrefix(name, "_")
+}
+
+// embedPatterns reads the go:embed patterns of the go files in dir
+func (watcher *Watcher) embedPatterns(dir string) []string {
+	if patterns, ok := watcher.embeds[dir]; ok {
+		return patterns
+	}
+	patterns := []string{}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, file := range files {
+		patterns = append(patterns, readEmbeds(file)...)
+	}
+	watcher.embeds[dir] = patterns
+	return patterns
+}
+
+// readEmbeds finds the patterns of the go:embed directives in a go file
+func readEmbeds(file string) []string {
+	src, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer src.Close()
+	patterns := []string{}
+	scanner := bufio.NewScanner(src)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "//go:embed ") {
+			continue
+		}
+		for _, field := range strings.Fields(strings.TrimPrefix(line, "//go:embed ")) {
+			if unquoted, err := strconv.Unquote(field); err == nil {
+				field = unquoted
+			}
+			patterns = append(patterns, strings.TrimPrefix(field, "all:"))
+		}
+	}
+	return patterns
+}
+
+// embedMatch checks if an embed pattern matches a file relative to the package
+// or one of its parent directories, since embedding a directory embeds all of
+// the files in it.
+func embedMatch(pattern, rel string) bool {
+	for ; rel != "." && rel != "/"; rel = path.Dir(rel) {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// loadGitignore reads the patterns of the .gitignore in dir if there is one
+func (watcher *Watcher) loadGitignore(dir string) {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		delete(watcher.gitignores, dir)
+		return
+	}
+	defer file.Close()
+	rules := []gitignore{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := gitignore{negate: strings.HasPrefix(line, "!")}
+		line = strings.TrimPrefix(line, "!")
+		rule.dirOnly = strings.HasSuffix(line, "/")
+		pattern, err := glob.Compile(line)
+		if err != nil {
+			continue
+		}
+		rule.pattern = pattern
+		rules = append(rules, rule)
+	}
+	watcher.gitignores[dir] = rules
 }
 
 func sum(src string) (string, error) {
@@ -0,0 +1,177 @@
+package watch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Run("notify", func(t *testing.T) {
+		backend, err := NewNotify()
+		assert.Nil(t, err)
+		testWatcher(t, backend)
+	})
+	t.Run("poll", func(t *testing.T) {
+		testWatcher(t, NewPoller(10*time.Millisecond))
+	})
+}
+
+func testWatcher(t *testing.T, backend Backend) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	assert.Nil(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	for _, path := range []string{".git/objects", "vendor/dep", "gen/proto", "skip", "lib/keep", "lib/out"} {
+		assert.Nil(t, os.MkdirAll(path, 0755))
+	}
+	assert.Nil(t, os.WriteFile(".gitignore", []byte("# generated\ngen/\n"), 0644))
+	assert.Nil(t, os.WriteFile("lib/.gitignore", []byte("*\n!keep\n"), 0644))
+
+	watcher, err := NewWithBackend(backend, []string{"skip"})
+	assert.Nil(t, err)
+	defer watcher.Close()
+	assert.Equal(t, map[string]bool{".": true, "lib": true, filepath.Join("lib", "keep"): true}, watcher.dirs)
+	assert.True(t, watcher.ignored(filepath.Join("lib", "a.go"), false))
+	assert.False(t, watcher.ignored("a.go", false))
+
+	go watcher.Start()
+	assert.Nil(t, os.MkdirAll(filepath.Join("pkg", "sub"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join("pkg", "sub", "a.go"), []byte("package sub\n"), 0644))
+	select {
+	case path := <-watcher.Changes:
+		assert.Equal(t, filepath.Join("pkg", "sub"), path)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change for a file in a new directory")
+	}
+	assert.True(t, watcher.dirs[filepath.Join("pkg", "sub")])
+}
+
+// limitBackend fails to add more than limit directories, like an inotify
+// watcher that reached the watch limit
+type limitBackend struct {
+	limit  int
+	dirs   int
+	errors chan error
+}
+
+func (backend *limitBackend) Add(dir string) error {
+	if backend.dirs++; backend.dirs > backend.limit {
+		return errors.New("no space left on device")
+	}
+	return nil
+}
+func (backend *limitBackend) Remove(dir string) error       { return nil }
+func (backend *limitBackend) Events() <-chan fsnotify.Event { return nil }
+func (backend *limitBackend) Errors() <-chan error          { return backend.errors }
+func (backend *limitBackend) Close() error                  { return nil }
+
+func TestWatcherFallback(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	assert.Nil(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	assert.Nil(t, os.MkdirAll("a", 0755))
+
+	t.Run("watch limit", func(t *testing.T) {
+		watcher, err := NewWithBackend(&limitBackend{limit: 1}, nil)
+		assert.Nil(t, err)
+		defer watcher.Close()
+		assert.True(t, watcher.Polling)
+		assert.EqualError(t, <-watcher.Fallback, "no space left on device")
+		assert.Equal(t, map[string]bool{".": true, "a": true}, watcher.dirs)
+
+		go watcher.Start()
+		assert.Nil(t, os.WriteFile(filepath.Join("a", "a.go"), []byte("package a\n"), 0644))
+		select {
+		case path := <-watcher.Changes:
+			assert.Equal(t, "a", path)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no change after switching to polling")
+		}
+	})
+
+	t.Run("backend error", func(t *testing.T) {
+		backend := &limitBackend{limit: 10, errors: make(chan error)}
+		watcher, err := NewWithBackend(backend, nil)
+		assert.Nil(t, err)
+		defer watcher.Close()
+		assert.False(t, watcher.Polling)
+
+		go watcher.Start()
+		backend.errors <- errors.New("queue overflow")
+		select {
+		case err := <-watcher.Fallback:
+			assert.EqualError(t, err, "queue overflow")
+		case <-time.After(5 * time.Second):
+			t.Fatal("did not switch to polling")
+		}
+		assert.Nil(t, os.WriteFile(filepath.Join("a", "b.go"), []byte("package a\n"), 0644))
+		select {
+		case path := <-watcher.Changes:
+			assert.Equal(t, "a", path)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no change after switching to polling")
+		}
+	})
+}
+
+func TestOwner(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	assert.Nil(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	assert.Nil(t, os.MkdirAll(filepath.Join("cmd", "templates"), 0755))
+	src := "package cmd\n\nimport \"embed\"\n\n//go:embed templates/*.tmpl \"static\"\nvar files embed.FS\n"
+	assert.Nil(t, os.WriteFile(filepath.Join("cmd", "cmd.go"), []byte(src), 0644))
+
+	watcher := &Watcher{embeds: map[string][]string{}}
+	cases := []struct {
+		path  string
+		owner string
+		ok    bool
+	}{
+		{path: "go.mod", owner: Everything, ok: true},
+		{path: filepath.Join("lib", "go.sum"), owner: Everything, ok: true},
+		{path: filepath.Join("lib", "testdata", "golden", "a.golden"), owner: "lib", ok: true},
+		{path: filepath.Join("testdata", "a.json"), owner: ".", ok: true},
+		{path: filepath.Join("lib", "testdata", "src", "a.go"), owner: "lib", ok: true},
+		{path: filepath.Join("_testdata", "go.go"), owner: ".", ok: true},
+		{path: filepath.Join("cmd", "templates", "a.tmpl"), owner: "cmd", ok: true},
+		{path: filepath.Join("cmd", "static", "css", "a.css"), owner: "cmd", ok: true},
+		{path: filepath.Join("cmd", "templates", "a.txt"), ok: false},
+		{path: "README.md", ok: false},
+	}
+	for _, testcase := range cases {
+		owner, ok := watcher.owner(testcase.path)
+		assert.Equal(t, testcase.ok, ok, testcase.path)
+		assert.Equal(t, testcase.owner, owner, testcase.path)
+	}
+}
+
+func TestOnChange(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	assert.Nil(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	assert.Nil(t, os.MkdirAll("lib", 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join("lib", "a_test.go"), []byte("package lib\n"), 0644))
+
+	watcher := &Watcher{Changes: make(chan string, 1), embeds: map[string][]string{}}
+	for path, change := range map[string]string{
+		filepath.Join("lib", "a.go"):                    filepath.Join("lib", "a_test.go"),
+		filepath.Join("lib", "b.go"):                    "lib",
+		filepath.Join("lib", "testdata", "src", "a.go"): "lib",
+		filepath.Join("_testdata", "go.go"):             ".",
+	} {
+		watcher.onChange(path)
+		assert.Equal(t, change, <-watcher.Changes, path)
+	}
+}